@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"durn/server/service"
+)
+
+const (
+	ballotsUsage = "ballots export <election-id> [-format json|blt|soc|soi|csv] [-o file]"
+	recountUsage = "recount [-method schulze|irv] [-json] <file|->"
+)
+
+// ballots exports the anonymised ballots of a finalized election. Ballots
+// exported as json can be counted again with recount
+func ballots(args []string) error {
+	if len(args) < 2 || args[0] != "export" {
+		return errUsage(ballotsUsage)
+	}
+
+	flags := flag.NewFlagSet("ballots export", flag.ContinueOnError)
+	output := flags.String("o", "-", "file to write the ballots to, - for stdout")
+	formatName := flags.String("format", "json", "file format, json, blt, soc, soi or csv")
+	if err := flags.Parse(args[2:]); err != nil {
+		return err
+	}
+	format, ok := service.BallotFormats[*formatName]
+	if !ok {
+		return fmt.Errorf("unknown format '%s'", *formatName)
+	}
+	electionId, err := parseElectionId(args[1:2], ballotsUsage)
+	if err != nil {
+		return err
+	}
+
+	repo, err := repository()
+	if err != nil {
+		return err
+	}
+
+	export, err := service.NewVotes(repo).Export(electionId, time.Now())
+	if err != nil {
+		return err
+	}
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return format.Write(out, export)
+}
+
+// recount counts the ballots of an exported ballot file, using the same
+// counting code as the server and skipping withdrawn candidates. Does not
+// need a database, so that anyone with the file can verify the result
+func recount(args []string) error {
+	flags := flag.NewFlagSet("recount", flag.ContinueOnError)
+	method := flags.String("method", "schulze", "counting method, schulze or irv")
+	asJson := flags.Bool("json", false, "print the full result as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage(recountUsage)
+	}
+
+	var in io.Reader = os.Stdin
+	if flags.Arg(0) != "-" {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	export, err := service.ReadBallotExport(in)
+	if err != nil {
+		return err
+	}
+	candidates, ballots := service.RemoveWithdrawn(export.CandidateList(), export.Ballots)
+
+	switch *method {
+	case "schulze":
+		result := service.Schulze(candidates, ballots, export.Weights)
+		if *asJson {
+			return writeJson("-", result)
+		}
+		fmt.Printf("Election: %s (%s)\n", export.Election.Name, export.Election.ID)
+		return printRanking(result)
+	case "irv":
+		result := service.IRV(candidates, ballots, export.Weights)
+		if *asJson {
+			return writeJson("-", result)
+		}
+		fmt.Printf("Election: %s (%s)\n", export.Election.Name, export.Election.ID)
+		return printIRV(result)
+	default:
+		return errUsage(recountUsage)
+	}
+}
+
+// printRanking prints the ranking of a Schulze count. Candidates that are tied
+// are marked, since their order is decided randomly and may differ between counts
+func printRanking(result service.SchulzeResult) error {
+	if result.Validity != nil {
+		printValidity(*result.Validity)
+	}
+	fmt.Printf("Total votes: %s\n", weighted(result.TotalVotes, result.WeightedVotes))
+	if result.Blanks > 0 {
+		fmt.Printf("Blank votes: %s\n", weighted(result.Blanks, result.WeightedBlanks))
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tCANDIDATE\t")
+	for i, candidate := range result.Ranking {
+		tied := ""
+		p := result.SchultzeMatrix
+		if i+1 < len(p) && p[i][i+1] == p[i+1][i] {
+			tied = "tied with next"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", i+1, candidate.Name, tied)
+	}
+	return w.Flush()
+}
+
+// printIRV prints the votes of each candidate in every round of an IRV count.
+// The candidate with the most votes in the last round is elected
+func printIRV(stages []service.IRVStage) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for i, stage := range stages {
+		last := i == len(stages)-1
+		fmt.Fprintf(w, "Round %d\tblank: %s\t\n", i+1, weighted(stage.Blanks, stage.WeightedBlanks))
+		for j, candidate := range stage.Candidates {
+			status := ""
+			if last && j == 0 {
+				status = "elected"
+			} else if !last && candidate.Eliminated {
+				status = "eliminated"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", candidate.Name, weighted(candidate.Votes, candidate.WeightedVotes), status)
+		}
+	}
+	return w.Flush()
+}
+
+// printValidity prints whether an election is valid, or why it is void, and
+// the rules that are not checked yet
+func printValidity(validity service.Validity) {
+	if validity.Valid && len(validity.Pending) > 0 {
+		fmt.Println("The election is valid so far")
+	} else if validity.Valid {
+		fmt.Println("The election is valid")
+	} else {
+		fmt.Println("The election is void:")
+		for _, reason := range validity.Reasons {
+			fmt.Printf("  %s\n", reason)
+		}
+	}
+	if len(validity.Pending) > 0 {
+		fmt.Println("Not checked until voting closes:")
+		for _, rule := range validity.Pending {
+			fmt.Printf("  %s\n", rule)
+		}
+	}
+}
+
+// weighted formats an amount of votes, followed by their total weight if it
+// is not the same
+func weighted(votes int, weight int) string {
+	if votes == weight {
+		return strconv.Itoa(votes)
+	}
+	return fmt.Sprintf("%d (weight %d)", votes, weight)
+}
+
+// createOutput creates the file, or returns stdout if the file is -
+func createOutput(output string) (io.WriteCloser, error) {
+	if output == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(output)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// writeJson writes the value as indented JSON to the file, or to stdout if
+// the file is -
+func writeJson(output string, value any) error {
+	out, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,80 @@
+// Package cli implements the subcommands of the durn binary, used to
+// administrate the system without going through the HTTP API
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	database "durn/server/db"
+	"durn/server/service"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"migrate":      {"migrate up|down|status [-n steps]", migrate},
+	"election":     {electionUsage, election},
+	"voters":       {votersUsage, voters},
+	"results":      {resultsUsage, results},
+	"ballots":      {ballotsUsage, ballots},
+	"recount":      {recountUsage, recount},
+	"receipts":     {receiptsUsage, receipts},
+	"election-key": {electionKeyUsage, electionKey},
+}
+
+// Run runs the subcommand given by the arguments (excluding the program name)
+// and returns the exit code
+func Run(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return 2
+	}
+	if args[0] == "help" {
+		printUsage()
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command '%s'\n", args[0])
+		printUsage()
+		return 2
+	}
+	if err := cmd.run(args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// repository connects to the database and returns a repository for it,
+// failing if the schema does not have the version expected by the code
+func repository() (service.Repository, error) {
+	if err := database.InitDB(); err != nil {
+		return nil, err
+	}
+	return service.NewGormRepository(database.GetDB()), nil
+}
+
+// errUsage is returned by commands when called with invalid arguments
+func errUsage(usage string) error {
+	return fmt.Errorf("usage: durn %s", usage)
+}
+
+func printUsage() {
+	var usages []string
+	for _, cmd := range commands {
+		usages = append(usages, cmd.usage)
+	}
+	sort.Strings(usages)
+
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  durn [serve]")
+	for _, usage := range usages {
+		fmt.Fprintf(os.Stderr, "  durn %s\n", usage)
+	}
+}
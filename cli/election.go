@@ -0,0 +1,289 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/service"
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const electionUsage = "election list|create|import|encrypt|finalize|count"
+
+// election manages elections, see the functions for each action
+func election(args []string) error {
+	if len(args) == 0 {
+		return errUsage(electionUsage)
+	}
+
+	repo, err := repository()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		return electionList(service.NewElections(repo))
+	case "create":
+		return electionCreate(service.NewElections(repo), args[1:])
+	case "import":
+		return electionImport(service.NewElections(repo), args[1:])
+	case "encrypt":
+		return electionEncrypt(service.NewElections(repo), args[1:])
+	case "finalize":
+		return electionFinalize(service.NewElections(repo), service.NewVotes(repo), args[1:])
+	case "count":
+		return electionCount(service.NewVotes(repo), args[1:])
+	default:
+		return errUsage(electionUsage)
+	}
+}
+
+// electionList prints all elections
+func electionList(elections *service.Elections) error {
+	list, err := elections.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tOPENS\tCLOSES\tCANDIDATES\tFINALIZED")
+	for _, e := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%t\n",
+			e.ID, e.Name, formatTime(e.OpenTime.Time, e.OpenTime.Valid),
+			formatTime(e.CloseTime.Time, e.CloseTime.Valid), len(e.Candidates), e.Finalized,
+		)
+	}
+	return w.Flush()
+}
+
+// electionCreate creates an election. Times are given in RFC 3339 format,
+// e.g. 2006-01-02T15:04:05+01:00
+func electionCreate(elections *service.Elections, args []string) error {
+	params := service.DefaultElectionParams()
+
+	flags := flag.NewFlagSet("election create", flag.ContinueOnError)
+	flags.StringVar(&params.Name, "name", params.Name, "name of the election")
+	flags.StringVar(&params.Description, "description", params.Description, "description of the election")
+	flags.IntVar(&params.Mandates, "mandates", params.Mandates, "amount of mandates")
+	flags.IntVar(&params.ExtraMandates, "extra-mandates", params.ExtraMandates, "amount of extra mandates")
+	flags.BoolVar(&params.ShuffleCandidates, "shuffle-candidates", params.ShuffleCandidates, "show candidates in a random order for each voter")
+	flags.IntVar(&params.MinTurnout, "min-turnout", 0, "least amount of votes for the election to be valid")
+	flags.Float64Var(&params.MinTurnoutShare, "min-turnout-share", 0, "least share of the voter roll, from 0 to 1, that must vote for the election to be valid")
+	flags.Float64Var(&params.MinNonBlankShare, "min-non-blank-share", 0, "least share of the votes, from 0 to 1, that must not be blank for the election to be valid")
+	openTime := flags.String("open", "", "time when voting opens (RFC 3339)")
+	closeTime := flags.String("close", "", "time when voting closes (RFC 3339)")
+	symbolic := flags.String("symbolic", "vacant", "comma separated symbolic candidates, vacant, reopen and blank")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	params.SymbolicCandidates = nil
+	for _, kind := range strings.Split(*symbolic, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			params.SymbolicCandidates = append(params.SymbolicCandidates, database.SymbolicKind(kind))
+		}
+	}
+
+	var err error
+	if params.OpenTime, err = parseNullTime(*openTime); err != nil {
+		return err
+	}
+	if params.CloseTime, err = parseNullTime(*closeTime); err != nil {
+		return err
+	}
+
+	election, err := elections.Create(params)
+	if err != nil {
+		return err
+	}
+	fmt.Println(election.ID)
+	return nil
+}
+
+// electionImport creates the elections and candidates described by a YAML or
+// JSON file, in the same format as the import endpoint. Files ending in .json
+// are read as JSON and all other files as YAML
+func electionImport(elections *service.Elections, args []string) error {
+	if len(args) != 1 {
+		return errUsage("election import <file|->")
+	}
+
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(args[0], ".json") {
+		if data, err = util.YamlToJson(data); err != nil {
+			return err
+		}
+	}
+
+	doc, err := service.ParseImport(data)
+	if err != nil {
+		return err
+	}
+	created, err := elections.Import(doc)
+	var importErrors service.ImportErrors
+	if errors.As(err, &importErrors) {
+		for _, e := range importErrors {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", e.Item, e.Error)
+		}
+		return fmt.Errorf("found %d problems, nothing was imported", len(importErrors))
+	} else if err != nil {
+		return err
+	}
+
+	for _, election := range created {
+		fmt.Printf("%s\t%s\n", election.ID, election.Name)
+	}
+	return nil
+}
+
+// electionEncrypt enables encryption of the ballots of an election with a
+// public key created by `durn election-key generate`
+func electionEncrypt(elections *service.Elections, args []string) error {
+	const usage = "election encrypt <election-id> -public-key <key> -threshold <k>"
+	if len(args) == 0 {
+		return errUsage(usage)
+	}
+	flags := flag.NewFlagSet("election encrypt", flag.ContinueOnError)
+	publicKey := flags.String("public-key", "", "base64 public key that the ballots are encrypted to")
+	threshold := flags.Int("threshold", 0, "amount of key shares needed to decrypt the ballots")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	electionId, err := parseElectionId(args[:1], usage)
+	if err != nil {
+		return err
+	}
+
+	election, err := elections.EnableEncryption(electionId, *publicKey, *threshold)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Encrypting the ballots of election '%s'\n", election.Name)
+	return nil
+}
+
+// electionFinalize finalizes an election, ending voting, and prints whether it
+// is valid. Elections with encrypted ballots need the key
+// shares, which are read one per line from a file or stdin so that they do not
+// end up in the shell history or the process list
+func electionFinalize(elections *service.Elections, votes *service.Votes, args []string) error {
+	const usage = "election finalize <election-id> [-shares <file>]"
+	if len(args) == 0 {
+		return errUsage(usage)
+	}
+	flags := flag.NewFlagSet("election finalize", flag.ContinueOnError)
+	sharesFile := flags.String("shares", "", "file with one key share per line, - for stdin, for elections with encrypted ballots")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	electionId, err := parseElectionId(args[:1], usage)
+	if err != nil {
+		return err
+	}
+	var shares []string
+	if *sharesFile != "" {
+		if shares, err = readShares(*sharesFile); err != nil {
+			return err
+		}
+	}
+
+	election, err := elections.Finalize(electionId, shares)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Finalized election '%s'\n", election.Name)
+	validity, err := votes.Validity(electionId, time.Now())
+	if err != nil {
+		return err
+	}
+	printValidity(validity)
+	return nil
+}
+
+// readShares reads key shares, one per line, skipping empty lines and
+// comments. Reads from stdin if the file is "-"
+func readShares(file string) ([]string, error) {
+	var in io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var shares []string
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		shares = append(shares, line)
+	}
+	return shares, scanner.Err()
+}
+
+// electionCount counts the votes of a finalized election and prints the ranking
+func electionCount(votes *service.Votes, args []string) error {
+	electionId, err := parseElectionId(args, "election count <election-id>")
+	if err != nil {
+		return err
+	}
+
+	result, err := votes.CountSchulze(electionId, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return printRanking(result)
+}
+
+func parseElectionId(args []string, usage string) (uuid.UUID, error) {
+	if len(args) != 1 {
+		return uuid.Nil, errUsage(usage)
+	}
+	electionId, err := uuid.FromString(args[0])
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", util.BadUUIDMessage, err)
+	}
+	return electionId, nil
+}
+
+func parseNullTime(value string) (util.NullTime, error) {
+	if value == "" {
+		return util.NullTime{Valid: false}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return util.NullTime{}, err
+	}
+	return util.NullTime{Time: t, Valid: true}, nil
+}
+
+func formatTime(t time.Time, valid bool) string {
+	if !valid {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"durn/server/service"
+)
+
+const electionKeyUsage = "election-key generate -shares <n> -threshold <k>"
+
+// electionKey generates keys for encrypting ballots and splits them into
+// shares. Does not need the database, so that the election committee can run
+// it on their own machine and only give the public key to the server
+func electionKey(args []string) error {
+	if len(args) == 0 || args[0] != "generate" {
+		return errUsage(electionKeyUsage)
+	}
+	flags := flag.NewFlagSet("election-key generate", flag.ContinueOnError)
+	shares := flags.Int("shares", 0, "amount of key shares to create")
+	threshold := flags.Int("threshold", 0, "amount of key shares needed to decrypt the ballots")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	key, err := service.GenerateElectionKey(*shares, *threshold)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Give one share to each member of the election committee, %d of them are needed to finalize the election. The private key is not kept anywhere else.\n", key.Threshold)
+	fmt.Fprintf(os.Stderr, "Encrypt the election with: durn election encrypt <election-id> -public-key %s -threshold %d\n", key.PublicKey, key.Threshold)
+	fmt.Printf("# public key: %s\n", key.PublicKey)
+	for _, share := range key.Shares {
+		fmt.Println(share)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	database "durn/server/db"
+)
+
+// migrate applies or reverts database migrations, or shows the current
+// schema version. Up applies all pending migrations and down reverts the
+// latest migration, unless another amount is given with -n
+func migrate(args []string) error {
+	if len(args) == 0 {
+		return errUsage("migrate up|down|status [-n steps]")
+	}
+	action := args[0]
+
+	flags := flag.NewFlagSet("migrate "+action, flag.ContinueOnError)
+	steps := flags.Int("n", 0, "amount of migrations to apply or revert")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if err := database.Connect(); err != nil {
+		return err
+	}
+	db := database.GetDB()
+	defer database.ReleaseDB()
+
+	latest, err := database.LatestVersion()
+	if err != nil {
+		return err
+	}
+
+	var version int
+	switch action {
+	case "up":
+		version, err = database.MigrateUp(db, *steps)
+	case "down":
+		if *steps == 0 {
+			*steps = 1
+		}
+		version, err = database.MigrateDown(db, *steps)
+	case "status":
+		version, err = database.SchemaVersion(db)
+	default:
+		return fmt.Errorf("unknown migrate action '%s'", action)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Schema is at version %d, code expects version %d\n", version, latest)
+	return nil
+}
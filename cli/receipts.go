@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+
+	"durn/server/service"
+)
+
+const receiptsUsage = "receipts keygen"
+
+// receipts generates keys for signing receipts. Does not need the database
+func receipts(args []string) error {
+	if len(args) != 1 || args[0] != "keygen" {
+		return errUsage(receiptsUsage)
+	}
+
+	key, err := service.GenerateReceiptKey()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("RECEIPT_KEY=%s\n", service.EncodeReceiptKey(key))
+	fmt.Printf("# public key: %s\n", base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)))
+	return nil
+}
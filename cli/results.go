@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"flag"
+	"time"
+
+	"durn/server/service"
+)
+
+const resultsUsage = "results export <election-id> [-o file]"
+
+// results exports the result of a finalized election as JSON, in the same
+// format as the count endpoint
+func results(args []string) error {
+	if len(args) < 2 || args[0] != "export" {
+		return errUsage(resultsUsage)
+	}
+
+	flags := flag.NewFlagSet("results export", flag.ContinueOnError)
+	output := flags.String("o", "-", "file to write the result to, - for stdout")
+	if err := flags.Parse(args[2:]); err != nil {
+		return err
+	}
+	electionId, err := parseElectionId(args[1:2], resultsUsage)
+	if err != nil {
+		return err
+	}
+
+	repo, err := repository()
+	if err != nil {
+		return err
+	}
+
+	result, err := service.NewVotes(repo).CountSchulze(electionId, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return writeJson(*output, result)
+}
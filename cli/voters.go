@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"durn/server/service"
+	"durn/server/util"
+)
+
+const votersUsage = "voters import [-locale sv|en] [-weight n] <file>|list"
+
+// voters manages the voter roll
+func voters(args []string) error {
+	if len(args) == 0 {
+		return errUsage(votersUsage)
+	}
+
+	repo, err := repository()
+	if err != nil {
+		return err
+	}
+	voters := service.NewVoters(repo)
+
+	switch args[0] {
+	case "import":
+		flags := flag.NewFlagSet("voters import", flag.ContinueOnError)
+		locale := flags.String("locale", util.DefaultLocale, "language of the notifications to the voters")
+		weightFlag := flags.Int("weight", 1, "how many votes the vote of each voter counts as, kept for voters already in the roll if not given")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errUsage("voters import [-locale sv|en] [-weight n] <file>")
+		}
+		var weight *int
+		flags.Visit(func(f *flag.Flag) {
+			if f.Name == "weight" {
+				weight = weightFlag
+			}
+		})
+		return votersImport(voters, flags.Arg(0), *locale, weight)
+	case "list":
+		return votersList(voters)
+	default:
+		return errUsage(votersUsage)
+	}
+}
+
+// votersImport adds the email addresses in a file, one per line, to the
+// voter roll with the weight, if given. Reads from stdin if the file is "-"
+func votersImport(voters *service.Voters, file string, locale string, weight *int) error {
+	var in io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var emails []string
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		email := strings.TrimSpace(scanner.Text())
+		if email == "" {
+			continue
+		}
+		if !util.ValidEmail(email) {
+			fmt.Fprintf(os.Stderr, "Skipping invalid email address '%s'\n", email)
+			continue
+		}
+		emails = append(emails, email)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if err := voters.Add(emails, locale, weight); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d voters\n", len(emails))
+	return nil
+}
+
+// votersList prints the email addresses of all voters, followed by the
+// weight for voters whose weight is not 1
+func votersList(voters *service.Voters) error {
+	list, err := voters.List()
+	if err != nil {
+		return err
+	}
+	weights, err := voters.Weights()
+	if err != nil {
+		return err
+	}
+	for _, voter := range list {
+		if weight, ok := weights[voter]; ok {
+			fmt.Printf("%s\t%d\n", voter, weight)
+		} else {
+			fmt.Println(voter)
+		}
+	}
+	return nil
+}
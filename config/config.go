@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	// _ "github.com/joho/godotenv/autoload"
@@ -13,13 +14,52 @@ type Config struct {
 	HOST string
 	PORT int
 
+	AUTHENTICATION_PROVIDER string
+
 	LOGIN_URL string
 	LOGIN_KEY string
 
+	OIDC_ISSUER      string
+	OIDC_AUDIENCE    string
+	OIDC_JWKS_URL    string
+	OIDC_EMAIL_CLAIM string
+	OIDC_USER_CLAIM  string
+
+	AUTHORIZATION_PROVIDER string
+
 	HIVE_URL     string
 	HIVE_API_KEY string
 
+	LOCAL_ADMINS string
+
+	UPSTREAM_TIMEOUT          time.Duration
+	AUTH_CACHE_TTL            time.Duration
+	AUTH_CACHE_SIZE           int
+	CIRCUIT_BREAKER_THRESHOLD int
+	CIRCUIT_BREAKER_COOLDOWN  time.Duration
+
 	DATABASE_URL string
+
+	BLOB_DIR       string
+	MAX_IMAGE_SIZE int
+
+	PUBLIC_URL            string
+	MAILER                string
+	MAIL_FROM             string
+	MAIL_FILE             string
+	MAIL_INTERVAL         time.Duration
+	REMINDER_BEFORE_CLOSE time.Duration
+	SMTP_HOST             string
+	SMTP_PORT             int
+	SMTP_USERNAME         string
+	SMTP_PASSWORD         string
+
+	RECEIPT_KEY string
+
+	MAX_PROXIES int
+
+	STARTUP_RETRIES     int
+	STARTUP_RETRY_DELAY time.Duration
 }
 
 var (
@@ -46,6 +86,16 @@ func loadIntEnv(e string, def int) int {
 	return val
 }
 
+func loadDurationEnv(e string, def time.Duration) time.Duration {
+	strVal := loadStringEnv(e, def.String())
+	val, err := time.ParseDuration(strVal)
+	if err != nil {
+		fmt.Printf("FATAL: %s\n", err)
+		os.Exit(1)
+	}
+	return val
+}
+
 func GetConfig() *Config {
 	if loaded {
 		return &conf
@@ -59,13 +109,52 @@ func GetConfig() *Config {
 		HOST: loadStringEnv("HOST", "https://localhost.datasektionen.se"),
 		PORT: loadIntEnv("PORT", 3000),
 
+		AUTHENTICATION_PROVIDER: loadStringEnv("AUTHENTICATION_PROVIDER", "login"),
+
 		LOGIN_URL: loadStringEnv("LOGIN_URL", "https://login.datasektionen.se"),
 		LOGIN_KEY: loadStringEnv("LOGIN_KEY", ""),
 
+		OIDC_ISSUER:      loadStringEnv("OIDC_ISSUER", ""),
+		OIDC_AUDIENCE:    loadStringEnv("OIDC_AUDIENCE", ""),
+		OIDC_JWKS_URL:    loadStringEnv("OIDC_JWKS_URL", ""),
+		OIDC_EMAIL_CLAIM: loadStringEnv("OIDC_EMAIL_CLAIM", "email"),
+		OIDC_USER_CLAIM:  loadStringEnv("OIDC_USER_CLAIM", "sub"),
+
+		AUTHORIZATION_PROVIDER: loadStringEnv("AUTHORIZATION_PROVIDER", "hive"),
+
 		HIVE_URL:     loadStringEnv("HIVE_URL", "https://hive.datasektionen.se"),
 		HIVE_API_KEY: loadStringEnv("HIVE_API_KEY", ""),
 
+		LOCAL_ADMINS: loadStringEnv("LOCAL_ADMINS", ""),
+
+		UPSTREAM_TIMEOUT:          loadDurationEnv("UPSTREAM_TIMEOUT", 5*time.Second),
+		AUTH_CACHE_TTL:            loadDurationEnv("AUTH_CACHE_TTL", time.Minute),
+		AUTH_CACHE_SIZE:           loadIntEnv("AUTH_CACHE_SIZE", 1000),
+		CIRCUIT_BREAKER_THRESHOLD: loadIntEnv("CIRCUIT_BREAKER_THRESHOLD", 5),
+		CIRCUIT_BREAKER_COOLDOWN:  loadDurationEnv("CIRCUIT_BREAKER_COOLDOWN", 30*time.Second),
+
 		DATABASE_URL: loadStringEnv("DATABASE_URL", ""),
+
+		BLOB_DIR:       loadStringEnv("BLOB_DIR", "./blobs"),
+		MAX_IMAGE_SIZE: loadIntEnv("MAX_IMAGE_SIZE", 2*1024*1024),
+
+		PUBLIC_URL:            loadStringEnv("PUBLIC_URL", "https://durn.datasektionen.se"),
+		MAILER:                loadStringEnv("MAILER", "none"),
+		MAIL_FROM:             loadStringEnv("MAIL_FROM", "dUrn <durn@datasektionen.se>"),
+		MAIL_FILE:             loadStringEnv("MAIL_FILE", "./mail.log"),
+		MAIL_INTERVAL:         loadDurationEnv("MAIL_INTERVAL", 200*time.Millisecond),
+		REMINDER_BEFORE_CLOSE: loadDurationEnv("REMINDER_BEFORE_CLOSE", 24*time.Hour),
+		SMTP_HOST:             loadStringEnv("SMTP_HOST", ""),
+		SMTP_PORT:             loadIntEnv("SMTP_PORT", 587),
+		SMTP_USERNAME:         loadStringEnv("SMTP_USERNAME", ""),
+		SMTP_PASSWORD:         loadStringEnv("SMTP_PASSWORD", ""),
+
+		RECEIPT_KEY: loadStringEnv("RECEIPT_KEY", ""),
+
+		MAX_PROXIES: loadIntEnv("MAX_PROXIES", 1),
+
+		STARTUP_RETRIES:     loadIntEnv("STARTUP_RETRIES", 6),
+		STARTUP_RETRY_DELAY: loadDurationEnv("STARTUP_RETRY_DELAY", time.Second),
 	}
 
 	loaded = true
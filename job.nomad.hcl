@@ -15,6 +15,66 @@ job "durn" {
         "traefik.http.routers.durn.rule=Host(`durn.datasektionen.se`)",
         "traefik.http.routers.durn.tls.certresolver=default",
       ]
+
+      # Restart the task if it stops responding
+      check {
+        name     = "durn-alive"
+        type     = "http"
+        path     = "/api/healthz"
+        interval = "10s"
+        timeout  = "2s"
+
+        check_restart {
+          limit = 3
+          grace = "90s"
+        }
+      }
+
+      # Only route traffic to the task when the database, login and hive are reachable
+      check {
+        name     = "durn-ready"
+        type     = "http"
+        path     = "/api/readyz"
+        interval = "10s"
+        timeout  = "5s"
+      }
+    }
+
+    # Applies database migrations before the server is started, since the
+    # server refuses to start if the schema is behind the code
+    task "migrate" {
+      driver = "docker"
+
+      lifecycle {
+        hook    = "prestart"
+        sidecar = false
+      }
+
+      config {
+        image   = var.image_tag
+        command = "./durn"
+        args    = ["migrate", "up"]
+      }
+
+      template {
+        data        = <<ENV
+{{ with nomadVar "nomad/jobs/durn" }}
+DATABASE_URL=postgresql://durn:{{ .db_password }}@postgres.dsekt.internal:5432/durn
+LOGIN_KEY={{ .login_key }}
+HIVE_API_KEY={{ .hive_api_key }}
+{{ end }}
+PORT={{ env "NOMAD_PORT_http" }}
+HOST=0.0.0.0
+LOGIN_URL=https://sso.datasektionen.se/legacyapi
+HIVE_URL=https://hive.datasektionen.se
+ENV
+        destination = "local/.env"
+        env         = true
+      }
+
+      resources {
+        memory = 60
+      }
     }
 
     task "durn" {
@@ -31,6 +91,7 @@ job "durn" {
 DATABASE_URL=postgresql://durn:{{ .db_password }}@postgres.dsekt.internal:5432/durn
 LOGIN_KEY={{ .login_key }}
 HIVE_API_KEY={{ .hive_api_key }}
+RECEIPT_KEY={{ .receipt_key }}
 {{ end }}
 PORT={{ env "NOMAD_PORT_http" }}
 HOST=0.0.0.0
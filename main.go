@@ -2,22 +2,33 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/gin-gonic/contrib/static"
 	"github.com/gin-gonic/gin"
 
+	"durn/cli"
 	conf "durn/config"
 	server "durn/server"
+	"durn/server/middleware"
 )
 
 func main() {
-	r := gin.Default()
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
+	r := gin.New()
+	r.Use(middleware.Logger(), gin.Recovery())
 
 	r.Use(static.Serve("/", static.LocalFile("./dist", true)))
 	r.Static("/public", "./public")
 
 	api := r.Group("/api")
-	server.InitRoutes(api)
+	if err := server.InitRoutes(api); err != nil {
+		fmt.Println(err)
+		os.Exit(5)
+	}
 
 	r.Run(fmt.Sprintf(":%d", conf.GetConfig().PORT))
 }
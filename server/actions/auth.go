@@ -3,19 +3,24 @@ package actions
 import (
 	"net/http"
 
+	"durn/server/middleware"
+
 	"github.com/gin-gonic/gin"
 )
 
 // ValidateToken responds with the email and all permissions associated with a login token.
+// Scoped permissions are only included in permissions.
 // Assumes that Authorization and Authentication middleware has been run before this function.
 func ValidateToken(c *gin.Context) {
 
 	res := struct {
-		User  string   `json:"user"`
-		Perms []string `json:"perms"`
+		User        string                  `json:"user"`
+		Perms       []string                `json:"perms"`
+		Permissions []middleware.Permission `json:"permissions"`
 	}{
-		User:  c.GetString("user"),
-		Perms: c.Keys["perms"].([]string),
+		User:        c.GetString("user"),
+		Perms:       c.Keys["perms"].([]string),
+		Permissions: c.Keys["permissions"].([]middleware.Permission),
 	}
 
 	c.JSON(http.StatusOK, res)
@@ -0,0 +1,51 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"durn/server/util"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// GetBulletinBoard returns all ballots of a finalized election with the
+// commitments of their receipts and the Merkle root over them, see
+// service.BulletinBoard. The board can be counted with `durn recount`
+func GetBulletinBoard(c *gin.Context) {
+	electionId, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadUUIDMessage)
+		return
+	}
+
+	board, err := voteService.BulletinBoard(electionId, time.Now())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// GetBallotProof returns the proof that the ballot with the receipt
+// commitment is included in the bulletin board of a finalized election
+func GetBallotProof(c *gin.Context) {
+	electionId, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadUUIDMessage)
+		return
+	}
+
+	proof, err := voteService.Proof(electionId, c.Param("commitment"), time.Now())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, proof)
+}
@@ -6,38 +6,57 @@ import (
 	"time"
 
 	database "durn/server/db"
+	"durn/server/middleware"
+	"durn/server/service"
 	"durn/server/util"
 
 	"github.com/gin-gonic/gin"
 	uuid "github.com/satori/go.uuid"
-	"gorm.io/gorm"
 )
 
 type electionExportType struct {
-	ID            uuid.UUID            `json:"id"`
-	Name          string               `json:"name"`
-	Description   string               `json:"description"`
-	Published     bool                 `json:"published"`
-	Finalized     bool                 `json:"finalized"`
-	Mandates      int                  `json:"mandates"`
-	ExtraMandates int                  `json:"extraMandates"`
-	OpenTime      util.NullTime        `json:"openTime"`
-	CloseTime     util.NullTime        `json:"closeTime"`
-	Candidates    []database.Candidate `json:"candidates"`
+	ID                uuid.UUID             `json:"id"`
+	Name              string                `json:"name"`
+	Description       string                `json:"description"`
+	Published         bool                  `json:"published"`
+	Finalized         bool                  `json:"finalized"`
+	Mandates          int                   `json:"mandates"`
+	ExtraMandates     int                   `json:"extraMandates"`
+	ShuffleCandidates bool                  `json:"shuffleCandidates"`
+	EncryptionKey     string                `json:"encryptionKey"`
+	KeyThreshold      int                   `json:"keyThreshold"`
+	MinTurnout        int                   `json:"minTurnout"`
+	MinTurnoutShare   float64               `json:"minTurnoutShare"`
+	MinNonBlankShare  float64               `json:"minNonBlankShare"`
+	Electorate        int                   `json:"electorate"`
+	Translations      database.Translations `json:"translations"`
+	OpenTime          util.NullTime         `json:"openTime"`
+	CloseTime         util.NullTime         `json:"closeTime"`
+	BallotRoot        string                `json:"ballotRoot"`
+	Candidates        []database.Candidate  `json:"candidates"`
 }
 
 func convertElectionToExportType(election database.Election) electionExportType {
 	return electionExportType{
-		ID:            election.ID,
-		Name:          election.Name,
-		Description:   election.Description,
-		Published:     election.Published,
-		Finalized:     election.Finalized,
-		Mandates:      election.Mandates,
-		ExtraMandates: election.ExtraMandates,
-		OpenTime:      util.ConvertSqlNullTime(election.OpenTime),
-		CloseTime:     util.ConvertSqlNullTime(election.CloseTime),
-		Candidates:    election.Candidates,
+		ID:                election.ID,
+		Name:              election.Name,
+		Description:       election.Description,
+		Published:         election.Published,
+		Finalized:         election.Finalized,
+		Mandates:          election.Mandates,
+		ExtraMandates:     election.ExtraMandates,
+		ShuffleCandidates: election.ShuffleCandidates,
+		EncryptionKey:     election.EncryptionKey,
+		KeyThreshold:      election.KeyThreshold,
+		MinTurnout:        election.MinTurnout,
+		MinTurnoutShare:   election.MinTurnoutShare,
+		MinNonBlankShare:  election.MinNonBlankShare,
+		Electorate:        election.Electorate,
+		Translations:      election.Translations,
+		OpenTime:          util.ConvertSqlNullTime(election.OpenTime),
+		CloseTime:         util.ConvertSqlNullTime(election.CloseTime),
+		BallotRoot:        election.BallotRoot,
+		Candidates:        election.Candidates,
 	}
 }
 
@@ -49,60 +68,18 @@ func convertElectionToExportType(election database.Election) electionExportType
 // - OpenTime, CloseTime: null
 // - Published, Finalized: false
 func CreateElection(c *gin.Context) {
-	body := struct {
-		Name          string        `json:"name"`
-		Description   string        `json:"description"`
-		OpenTime      util.NullTime `json:"openTime"`
-		CloseTime     util.NullTime `json:"closeTime"`
-		Mandates      int           `json:"mandates"`
-		ExtraMandates int           `json:"extraMandates"`
-	}{
-		Name:          "",
-		Description:   "",
-		OpenTime:      util.NullTime{Valid: false},
-		CloseTime:     util.NullTime{Valid: false},
-		Mandates:      1,
-		ExtraMandates: 0,
-	}
+	body := service.DefaultElectionParams()
 	if err := c.BindJSON(&body); err != nil {
 		c.String(http.StatusBadRequest, util.BadParametersMessage)
 		return
 	}
 
-	db := database.GetDB()
-	defer database.ReleaseDB()
-	election := database.Election{
-		ID:            uuid.NewV4(),
-		Name:          body.Name,
-		Description:   body.Description,
-		Mandates:      body.Mandates,
-		ExtraMandates: body.ExtraMandates,
-		OpenTime:      util.ConvertNullTime(body.OpenTime),
-		CloseTime:     util.ConvertNullTime(body.CloseTime),
-		Published:     false,
-		Finalized:     false,
-	}
-	vacant := database.Candidate{
-		ID:           uuid.NewV4(),
-		Name:         util.VacantCandidate,
-		Presentation: "",
-		ElectionID:   election.ID,
-		Symbolic:     true,
-	}
-
-	if err := db.Transaction(func(tx *gorm.DB) error {
-		if err := db.Create(&election).Error; err != nil {
-			return err
-		}
-		if err := db.Create(&vacant).Error; err != nil {
-			return err
-		}
-		return nil
-	}); err != nil {
-		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
+	election, err := electionService.Create(body)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
+	publishElectionEvent(service.EventElectionCreated, election)
 
 	c.JSON(http.StatusOK, election.ID)
 }
@@ -112,14 +89,7 @@ func CreateElection(c *gin.Context) {
 // will not be affected in the database.
 // Allowed fields are: Name, Description, OpenTime, CloseTime
 func EditElection(c *gin.Context) {
-	body := struct {
-		Name          *string        `json:"name"`
-		Description   *string        `json:"description"`
-		OpenTime      *util.NullTime `json:"openTime"`
-		CloseTime     *util.NullTime `json:"closeTime"`
-		Mandates      *int           `json:"mandates"`
-		ExtraMandates *int           `json:"extraMandates"`
-	}{}
+	body := service.ElectionChanges{}
 	electionId, err := uuid.FromString(c.Param("id"))
 
 	if err != nil {
@@ -133,44 +103,17 @@ func EditElection(c *gin.Context) {
 		return
 	}
 
-	election := database.Election{ID: electionId}
-	db := database.GetDB()
-	defer database.ReleaseDB()
-	if err := db.Preload("Candidates").First(&election).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusBadRequest, util.InvalidElectionMessage)
-		return
-	}
-
-	if election.Finalized {
-		fmt.Println("User tried to edit election after it had been finalized")
-		c.String(http.StatusBadRequest, "Can't edit finalized election")
+	before, err := electionService.Get(electionId)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
-
-	if body.Name != nil {
-		election.Name = *body.Name
-	}
-	if body.Description != nil {
-		election.Description = *body.Description
-	}
-	if body.OpenTime != nil {
-		election.OpenTime = util.ConvertNullTime(*body.OpenTime)
-	}
-	if body.CloseTime != nil {
-		election.CloseTime = util.ConvertNullTime(*body.CloseTime)
-	}
-	if body.Mandates != nil {
-		election.Mandates = *body.Mandates
-	}
-	if body.ExtraMandates != nil {
-		election.ExtraMandates = *body.ExtraMandates
-	}
-	if err := db.Save(&election).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
+	election, err := electionService.Edit(electionId, body)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
+	publishElectionEdit(before, election)
 	c.JSON(http.StatusOK, convertElectionToExportType(election))
 }
 
@@ -183,20 +126,16 @@ func setElectionPublishedStatus(c *gin.Context, publishedStatus bool) {
 		return
 	}
 
-	election := database.Election{ID: electionId}
-	db := database.GetDB()
-	defer database.ReleaseDB()
-	if err := db.Preload("Candidates").First(&election).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusBadRequest, util.InvalidElectionMessage)
+	election, err := electionService.SetPublished(electionId, publishedStatus)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
-
-	election.Published = publishedStatus
-	if err := db.Save(&election).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
-		return
+	if publishedStatus {
+		publishElectionEvent(service.EventElectionPublished, election)
+	} else {
+		// Voters that could see the election need to know that it is gone
+		eventService.Publish(service.Event{Type: service.EventElectionUnpublished, Election: election.ID, Public: true})
 	}
 
 	c.JSON(http.StatusOK, convertElectionToExportType(election))
@@ -213,32 +152,92 @@ func UnpublishElection(c *gin.Context) {
 }
 
 // FinalizeElection marks an election as finalized, meaning that voting is finished
-// and enabling vote counting.
+// and enabling vote counting. Elections with encrypted ballots need the key
+// shares in the body, as {"shares": [...]}. The response includes whether the
+// election is valid under its validity rules, where the rules that depend on
+// the ballots are pending until the close time.
 // Note that there is no endpoint for unfinalizing elections.
 func FinalizeElection(c *gin.Context) {
+	body := struct {
+		Shares []string `json:"shares"`
+	}{}
 	electionId, err := uuid.FromString(c.Param("id"))
 	if err != nil {
 		fmt.Println(err)
 		c.String(http.StatusBadRequest, util.BadUUIDMessage)
 		return
 	}
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&body); err != nil {
+			fmt.Println(err)
+			c.String(http.StatusBadRequest, util.BadParametersMessage)
+			return
+		}
+	}
+
+	election, err := electionService.Finalize(electionId, body.Shares)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	publishElectionEvent(service.EventElectionFinalized, election)
 
-	election := database.Election{ID: electionId}
-	db := database.GetDB()
-	defer database.ReleaseDB()
-	if err := db.Preload("Candidates").First(&election).Error; err != nil {
+	validity, err := voteService.Validity(electionId, time.Now())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, struct {
+		electionExportType
+		Validity service.Validity `json:"validity"`
+	}{convertElectionToExportType(election), validity})
+}
+
+// GetValidity responds with whether a finalized election is valid under its
+// validity rules, see service.Votes.Validity
+func GetValidity(c *gin.Context) {
+	electionId, err := uuid.FromString(c.Param("id"))
+	if err != nil {
 		fmt.Println(err)
-		c.String(http.StatusBadRequest, util.InvalidElectionMessage)
+		c.String(http.StatusBadRequest, util.BadUUIDMessage)
 		return
 	}
 
-	election.Finalized = true
-	if err := db.Save(&election).Error; err != nil {
+	validity, err := voteService.Validity(electionId, time.Now())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, validity)
+}
+
+// EncryptElection enables encryption of the ballots of an election without
+// votes, with {"publicKey": ..., "threshold": ...} in the body, see
+// service.Elections.EnableEncryption. The key shares are created by the
+// election committee with `durn election-key generate` and never sent here
+// before finalizing
+func EncryptElection(c *gin.Context) {
+	body := struct {
+		PublicKey string `json:"publicKey" binding:"required"`
+		Threshold int    `json:"threshold" binding:"required"`
+	}{}
+	electionId, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadUUIDMessage)
+		return
+	}
+	if err := c.BindJSON(&body); err != nil {
 		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
+		c.String(http.StatusBadRequest, util.BadParametersMessage)
 		return
 	}
 
+	election, err := electionService.EnableEncryption(electionId, body.PublicKey, body.Threshold)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, convertElectionToExportType(election))
 }
 
@@ -252,31 +251,16 @@ func DeleteElection(c *gin.Context) {
 		return
 	}
 
-	election := database.Election{ID: electionId}
-	db := database.GetDB()
-	defer database.ReleaseDB()
-	if err := db.Preload("Votes").First(&election).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusBadRequest, util.InvalidElectionMessage)
+	election, err := electionService.Get(electionId)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
-
-	// if len(election.Votes) > 0 {
-	// 	c.String(http.StatusBadRequest, "Can't delete election with votes")
-	// 	return
-	// }
-	if err := db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("election_id = ?", electionId).Delete(&database.Candidate{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Delete(&election).Error; err != nil {
-			return err
-		}
-		return nil
-	}); err != nil {
-		fmt.Println(err)
-		c.String(http.StatusBadRequest, util.RequestFailedMessage)
+	if err := electionService.Delete(electionId); err != nil {
+		respondError(c, err)
+		return
 	}
+	publishElectionEvent(service.EventElectionDeleted, election)
 
 	c.JSON(http.StatusOK, "")
 }
@@ -291,35 +275,29 @@ func GetElection(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
-	defer database.ReleaseDB()
-
-	election := database.Election{ID: electionId}
-	if err := db.Preload("Candidates").First(&election).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusBadRequest, util.InvalidElectionMessage)
+	election, err := electionService.Get(electionId)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, convertElectionToExportType(election))
 }
 
-// GetElections fetches all elections in the database, including all
-// candidates in the elections.
+// GetElections fetches all elections in the database that the user has
+// admin-read permissions for, including all candidates in the elections.
 func GetElections(c *gin.Context) {
-	db := database.GetDB()
-	defer database.ReleaseDB()
-
-	var elections []database.Election
-	if err := db.Preload("Candidates").Find(&elections).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusBadRequest, util.InvalidElectionMessage)
+	elections, err := electionService.List()
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	result := []electionExportType{}
 	for _, election := range elections {
-		result = append(result, convertElectionToExportType(election))
+		if middleware.Permitted(c, middleware.AdminReadPermission, election.ID.String()) {
+			result = append(result, convertElectionToExportType(election))
+		}
 	}
 	c.JSON(http.StatusOK, result)
 }
@@ -327,22 +305,17 @@ func GetElections(c *gin.Context) {
 // GetPublicElections fetches all elections in the database with the
 // published flag set to true.
 func GetPublicElections(c *gin.Context) {
-	db := database.GetDB()
-	defer database.ReleaseDB()
-
-	var elections []database.Election
-	if err := db.Preload("Candidates").Find(&elections).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
+	elections, err := electionService.ListOpen(time.Now())
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
+	locale := requestLocale(c)
 	result := []electionExportType{}
-	now := time.Now()
 	for _, election := range elections {
-		if util.TimeIsInValidInterval(now, election.OpenTime, election.CloseTime) {
-			result = append(result, convertElectionToExportType(election))
-		}
+		election.Candidates = service.BallotOrder(election, c.GetString("user"))
+		result = append(result, convertElectionToExportType(service.Localize(election, locale)))
 	}
 	c.JSON(http.StatusOK, result)
 }
@@ -359,32 +332,24 @@ func GetPublicElection(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
-	defer database.ReleaseDB()
-	// election := database.FetchElectionIfPublic(db, electionId)
-
-	election := database.Election{ID: electionId}
-	if err := db.Preload("Candidates").First(&election).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusBadRequest, util.InvalidElectionMessage)
+	election, err := electionService.Get(electionId)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
+	election.Candidates = service.BallotOrder(election, c.GetString("user"))
+	election = service.Localize(election, requestLocale(c))
 
 	c.JSON(http.StatusOK, convertElectionToExportType(election))
 }
 
-// AddCandidate adds a candidate to the specified election. The name parameter
-// needs to be specified, presentation is defaulted to "" if not present.
-// Note that candidates can not be added to elections after they have been published
-func AddCandidate(c *gin.Context) {
+// OrderCandidates sets the order of the candidates on the ballot. The body
+// contains the ids of all candidates that are not symbolic, in order
+func OrderCandidates(c *gin.Context) {
 	body := struct {
-		Name         string `json:"name" binding:"required"`
-		Presentation string `json:"presentation"`
-	}{
-		Presentation: "",
-	}
+		Order []uuid.UUID `json:"order" binding:"required"`
+	}{}
 	electionId, err := uuid.FromString(c.Param("id"))
-
 	if err != nil {
 		fmt.Println(err)
 		c.String(http.StatusBadRequest, util.BadUUIDMessage)
@@ -395,46 +360,64 @@ func AddCandidate(c *gin.Context) {
 		c.String(http.StatusBadRequest, util.BadParametersMessage)
 		return
 	}
-	if body.Name == util.BlankCandidate || body.Name == util.VacantCandidate {
-		c.String(http.StatusBadRequest, fmt.Sprintf("'%s' is a reserved candidate name", body.Name))
+
+	election, err := electionService.OrderCandidates(electionId, body.Order)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, convertElectionToExportType(election))
+}
 
-	db := database.GetDB()
-	defer database.ReleaseDB()
-
-	election := database.Election{ID: electionId}
-	if err := db.Preload("Votes").First(&election).Error; err != nil {
+// SetSymbolicCandidates sets which symbolic candidates the election has. The
+// body contains the kinds, e.g. {"symbolicCandidates": ["vacant", "blank"]}
+func SetSymbolicCandidates(c *gin.Context) {
+	body := struct {
+		SymbolicCandidates []database.SymbolicKind `json:"symbolicCandidates"`
+	}{}
+	electionId, err := uuid.FromString(c.Param("id"))
+	if err != nil {
 		fmt.Println(err)
-		c.String(http.StatusBadRequest, util.InvalidElectionMessage)
+		c.String(http.StatusBadRequest, util.BadUUIDMessage)
 		return
 	}
-
-	if election.Published || election.Finalized {
-		c.String(http.StatusBadRequest, "Can't add candidate to published or finalized Election")
+	if err := c.BindJSON(&body); err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadParametersMessage)
 		return
 	}
 
-	if election.OpenTime.Valid && time.Now().After(election.OpenTime.Time) {
-		c.String(http.StatusBadRequest, "Can't add candidate to opened Election")
+	election, err := electionService.SetSymbolicCandidates(electionId, body.SymbolicCandidates, time.Now())
+	if err != nil {
+		respondError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, convertElectionToExportType(election))
+}
 
-	if len(election.Votes) > 0 {
-		c.String(http.StatusBadRequest, "Can't add candidate to election with votes")
-		return
+// AddCandidate adds a candidate to the specified election. The name parameter
+// needs to be specified, presentation is defaulted to "" if not present.
+// Note that candidates can not be added to elections after they have been published
+func AddCandidate(c *gin.Context) {
+	body := service.CandidateParams{
+		Presentation: "",
 	}
+	electionId, err := uuid.FromString(c.Param("id"))
 
-	candidate := database.Candidate{
-		ID:           uuid.NewV4(),
-		Name:         body.Name,
-		Presentation: body.Presentation,
-		ElectionID:   electionId,
-		Symbolic:     false,
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadUUIDMessage)
+		return
 	}
-	if err := db.Create(&candidate).Error; err != nil {
+	if err := c.BindJSON(&body); err != nil {
 		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
+		c.String(http.StatusBadRequest, util.BadParametersMessage)
+		return
+	}
+
+	candidate, err := electionService.AddCandidate(electionId, body, time.Now())
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, candidate)
@@ -443,10 +426,7 @@ func AddCandidate(c *gin.Context) {
 // EditCandidate modifies the specified candidate. Fields that are not included in
 // request body will not be changed in the database
 func EditCandidate(c *gin.Context) {
-	body := struct {
-		Name         *string `json:"name"`
-		Presentation *string `json:"presentation"`
-	}{}
+	body := service.CandidateChanges{}
 	candidateId, err := uuid.FromString(c.Param("id"))
 
 	if err != nil {
@@ -460,24 +440,9 @@ func EditCandidate(c *gin.Context) {
 		return
 	}
 
-	candidate := database.Candidate{ID: candidateId}
-	db := database.GetDB()
-	defer database.ReleaseDB()
-	if err := db.First(&candidate).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusBadRequest, "Invalid candidate specified")
-		return
-	}
-
-	if body.Name != nil {
-		candidate.Name = *body.Name
-	}
-	if body.Presentation != nil {
-		candidate.Presentation = *body.Presentation
-	}
-	if err := db.Save(&candidate).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
+	candidate, err := electionService.EditCandidate(candidateId, body)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, candidate)
@@ -494,34 +459,8 @@ func RemoveCandidate(c *gin.Context) {
 		return
 	}
 
-	candidate := database.Candidate{ID: candidateId}
-	db := database.GetDB()
-	defer database.ReleaseDB()
-	if err := db.Preload("Election.Votes").First(&candidate).Error; err != nil {
-		c.String(http.StatusBadRequest, "Invalid candidate specified")
-		return
-	}
-
-	openTime := candidate.Election.OpenTime
-
-	if openTime.Valid && time.Now().After(openTime.Time) {
-		c.String(http.StatusBadRequest, "Can't remove candidate from opened election")
-		return
-	}
-
-	if candidate.Election.Finalized {
-		c.String(http.StatusBadRequest, "Can't remove candidate from finalized election")
-		return
-	}
-
-	if len(candidate.Election.Votes) > 0 {
-		c.String(http.StatusBadRequest, "Can't remove candidate from election with votes")
-		return
-	}
-
-	if err := db.Delete(&candidate).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
+	if err := electionService.RemoveCandidate(candidateId, time.Now()); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -0,0 +1,60 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"durn/server/service"
+)
+
+func TestEncryptedElection(t *testing.T) {
+	r := newTestRouter(t, "voter@kth.se")
+	r.POST("/election/:id/encrypt", EncryptElection)
+	r.PUT("/election/:id/finalize", FinalizeElection)
+	election := createTestElection(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	path := "/election/" + election.ID.String()
+
+	// The committee creates the key, and the server only gets the public key
+	keyShares, err := service.GenerateElectionKey(3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := request(r, "POST", path+"/encrypt", map[string]any{"publicKey": keyShares.PublicKey, "threshold": keyShares.Threshold})
+	if w.Code != http.StatusOK {
+		t.Fatalf("encrypt responded %d %q", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "shares") {
+		t.Errorf("encrypt responded with shares: %s", w.Body.String())
+	}
+
+	if w := request(r, "POST", path+"/vote", map[string]any{"ranking": rankingOf(election)}); w.Code != http.StatusOK {
+		t.Fatalf("vote responded %d %q", w.Code, w.Body.String())
+	}
+	// Voting has to close for the ballots to be shown, but they are still
+	// encrypted until the election is finalized
+	closeTestElection(t, election)
+	var votes []service.BallotRecord
+	if err := json.Unmarshal(request(r, "GET", path+"/votes", nil).Body.Bytes(), &votes); err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 1 || len(votes[0].Rankings) != 0 {
+		t.Errorf("votes before finalization = %+v, want one vote without rankings", votes)
+	}
+
+	if w := request(r, "PUT", path+"/finalize", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("finalize without shares responded %d %q, want %d", w.Code, w.Body.String(), http.StatusBadRequest)
+	}
+	w = request(r, "PUT", path+"/finalize", map[string]any{"shares": keyShares.Shares[1:]})
+	if w.Code != http.StatusOK {
+		t.Fatalf("finalize responded %d %q", w.Code, w.Body.String())
+	}
+	if err := json.Unmarshal(request(r, "GET", path+"/votes", nil).Body.Bytes(), &votes); err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 1 || len(votes[0].Rankings) != len(election.Candidates) {
+		t.Errorf("votes after finalization = %+v, want the decrypted ranking", votes)
+	}
+}
@@ -0,0 +1,132 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/middleware"
+	"durn/server/service"
+	"durn/server/util"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// heartbeatInterval is how often a comment is sent on idle event streams, so
+// that proxies do not close them
+const heartbeatInterval = 30 * time.Second
+
+// GetEvents streams the public events, such as voting opening or closing and
+// changes to elections that voters can see, as Server-Sent Events
+func GetEvents(c *gin.Context) {
+	streamEvents(c, true)
+}
+
+// GetAdminEvents streams the events of the elections the user can administer
+// as Server-Sent Events, including changes to unpublished elections and the
+// turnout after every vote. Public events of other elections are also sent
+func GetAdminEvents(c *gin.Context) {
+	streamEvents(c, false)
+}
+
+// canAdminister checks if the user has admin permissions for the election of
+// the event, scoped or not
+func canAdminister(c *gin.Context, event service.Event) bool {
+	election := ""
+	if event.Election != uuid.Nil {
+		election = event.Election.String()
+	}
+	return middleware.Permitted(c, middleware.AdminReadPermission, election) ||
+		middleware.Permitted(c, middleware.AdminWritePermission, election)
+}
+
+// streamEvents sends events to the client until it disconnects. Each event
+// is named by its type and has the event as JSON data
+func streamEvents(c *gin.Context, public bool) {
+	events, unsubscribe := eventService.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if event.Public || (!public && canAdminister(c, event)) {
+				c.SSEvent(event.Type, event)
+			}
+			return true
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// WatchVotingWindows publishes events when voting opens or closes. Never
+// returns
+func WatchVotingWindows(interval time.Duration) {
+	eventService.WatchVotingWindows(electionService, interval)
+}
+
+// visibleToVoters checks if voters can see the election, which they can if
+// it is published or open for voting
+func visibleToVoters(election database.Election) bool {
+	return election.Published || util.TimeIsInValidInterval(time.Now(), election.OpenTime, election.CloseTime)
+}
+
+// publishElectionEvent publishes a change to the election, which is public if
+// voters can see the election
+func publishElectionEvent(eventType string, election database.Election) {
+	eventService.Publish(service.Event{
+		Type:     eventType,
+		Election: election.ID,
+		Public:   visibleToVoters(election),
+	})
+}
+
+// publishElectionEdit publishes that an election was edited, and whether
+// voting opened or closed because its times were changed
+func publishElectionEdit(before database.Election, after database.Election) {
+	eventService.Publish(service.Event{
+		Type:     service.EventElectionUpdated,
+		Election: after.ID,
+		Public:   visibleToVoters(before) || visibleToVoters(after),
+	})
+
+	now := time.Now()
+	wasOpen := util.TimeIsInValidInterval(now, before.OpenTime, before.CloseTime)
+	isOpen := util.TimeIsInValidInterval(now, after.OpenTime, after.CloseTime)
+	if !wasOpen && isOpen {
+		eventService.Publish(service.Event{Type: service.EventVotingOpened, Election: after.ID, Public: true})
+	} else if wasOpen && !isOpen {
+		eventService.Publish(service.Event{Type: service.EventVotingClosed, Election: after.ID, Public: true})
+	}
+}
+
+// publishTurnout publishes the amount of votes in the election to admins
+func publishTurnout(electionId uuid.UUID) {
+	count, err := voteService.Count(electionId)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	eventService.Publish(service.Event{
+		Type:     service.EventTurnout,
+		Election: electionId,
+		Data:     gin.H{"votes": count},
+	})
+}
@@ -0,0 +1,93 @@
+package actions
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"durn/server/middleware"
+	"durn/server/service"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// readEvents connects to the event stream and returns a channel receiving the
+// names of the events
+func readEvents(t *testing.T, server *httptest.Server, path string) <-chan string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+path, nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/event-stream") {
+		t.Fatalf("Content-Type = %q, want text/event-stream", got)
+	}
+
+	names := make(chan string, 16)
+	go func() {
+		defer res.Body.Close()
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, "event:") {
+				names <- strings.TrimPrefix(line, "event:")
+			}
+		}
+	}()
+	return names
+}
+
+func nextEvent(t *testing.T, names <-chan string) string {
+	t.Helper()
+	select {
+	case name := <-names:
+		return name
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return ""
+	}
+}
+
+func TestEventStreams(t *testing.T) {
+	r := newTestRouter(t, "voter@kth.se")
+	election := createTestElection(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	r.GET("/events", GetEvents)
+	// The admin only administers this election, so events of other
+	// elections are only sent if they are public
+	r.GET("/events/admin", func(c *gin.Context) {
+		c.Set("permissions", []middleware.Permission{
+			{ID: middleware.AdminReadPermission, Scope: election.ID.String()},
+		})
+	}, GetAdminEvents)
+	// Closing the server waits for the streams to end, so it has to be
+	// cleaned up after the streams are disconnected by readEvents
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	public := readEvents(t, server, "/events")
+	admin := readEvents(t, server, "/events/admin")
+
+	eventService.Publish(service.Event{Type: service.EventElectionUpdated, Election: uuid.NewV4()})
+	if w := request(r, "POST", "/election/"+election.ID.String()+"/vote", map[string]any{"ranking": rankingOf(election)}); w.Code != http.StatusOK {
+		t.Fatalf("vote responded %d %q", w.Code, w.Body.String())
+	}
+	eventService.Publish(service.Event{Type: service.EventVotingClosed, Election: election.ID, Public: true})
+
+	if got := nextEvent(t, admin); got != service.EventTurnout {
+		t.Errorf("admin stream got %q, want %q", got, service.EventTurnout)
+	}
+	if got := nextEvent(t, admin); got != service.EventVotingClosed {
+		t.Errorf("admin stream got %q, want %q", got, service.EventVotingClosed)
+	}
+	// The turnout is not public, so the first event voters get is the closing
+	if got := nextEvent(t, public); got != service.EventVotingClosed {
+		t.Errorf("public stream got %q, want %q", got, service.EventVotingClosed)
+	}
+}
@@ -0,0 +1,44 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"durn/server/service"
+	"durn/server/util"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// ExportBallots returns the anonymised ballots of a finalized election as a
+// file in the format given by the format query parameter, see
+// service.BallotFormats. The default json format can be counted independently
+// with `durn recount`
+func ExportBallots(c *gin.Context) {
+	electionId, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadUUIDMessage)
+		return
+	}
+	format, ok := service.BallotFormats[c.DefaultQuery("format", "json")]
+	if !ok {
+		c.String(http.StatusBadRequest, "Unknown export format")
+		return
+	}
+
+	export, err := voteService.Export(electionId, time.Now())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="ballots-%s.%s"`, electionId, format.Extension))
+	c.Header("Content-Type", format.ContentType)
+	c.Status(http.StatusOK)
+	if err := format.Write(c.Writer, export); err != nil {
+		fmt.Println(err)
+	}
+}
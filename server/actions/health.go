@@ -0,0 +1,32 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz responds as long as the server is running, and is used as a
+// liveness check
+func Healthz(c *gin.Context) {
+	c.String(http.StatusOK, "ok")
+}
+
+// Readyz returns a handler that runs all the given checks, responding with
+// the result of each check. Responds with 503 if any check fails, so that
+// no traffic is sent to the server until the services it depends on are reachable
+func Readyz(checks map[string]func() error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := http.StatusOK
+		result := map[string]string{}
+		for name, check := range checks {
+			if err := check(); err != nil {
+				status = http.StatusServiceUnavailable
+				result[name] = err.Error()
+			} else {
+				result[name] = "ok"
+			}
+		}
+		c.JSON(status, result)
+	}
+}
@@ -0,0 +1,112 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+
+	"durn/server/service"
+	"durn/server/util"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// UploadCandidateImage sets the image of a candidate, replacing any previous
+// image. The image is sent as the field "image" of a multipart form, and can
+// be a png, jpeg, gif or webp image of at most MAX_IMAGE_SIZE bytes
+func UploadCandidateImage(c *gin.Context) {
+	candidateId, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadUUIDMessage)
+		return
+	}
+
+	// Leave some room for the rest of the form
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, imageService.MaxSize()+64*1024)
+	header, err := c.FormFile("image")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.String(http.StatusRequestEntityTooLarge, fmt.Sprintf("Image is larger than %d bytes", imageService.MaxSize()))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadParametersMessage)
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadParametersMessage)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, imageService.MaxSize()+1))
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadParametersMessage)
+		return
+	}
+
+	candidate, err := imageService.SetCandidateImage(candidateId, data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, candidate)
+}
+
+// RemoveCandidateImage removes the image of a candidate
+func RemoveCandidateImage(c *gin.Context) {
+	candidateId, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadUUIDMessage)
+		return
+	}
+
+	candidate, err := imageService.RemoveCandidateImage(candidateId)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, candidate)
+}
+
+// assetTypes are the content types assets are served with, by extension
+var assetTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// GetAsset serves an uploaded asset, such as the image of a candidate.
+// Assets are never changed, so they can be cached forever
+func GetAsset(c *gin.Context) {
+	name := c.Param("name")
+	contentType, ok := assetTypes[path.Ext(name)]
+	if !ok {
+		c.String(http.StatusNotFound, "Asset not found")
+		return
+	}
+
+	file, modified, err := imageService.Open(name)
+	if errors.Is(err, service.ErrNotFound) {
+		c.String(http.StatusNotFound, "Asset not found")
+		return
+	} else if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "default-src 'none'; sandbox")
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(c.Writer, c.Request, name, modified, file)
+}
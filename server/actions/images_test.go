@@ -0,0 +1,65 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	database "durn/server/db"
+)
+
+func uploadImage(r http.Handler, candidateId string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("image", "bild.png")
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/election/candidate/"+candidateId+"/image", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCandidateImage(t *testing.T) {
+	r := newTestRouter(t, "admin@kth.se")
+	r.POST("/election/candidate/:id/image", UploadCandidateImage)
+	r.GET("/assets/:name", GetAsset)
+	election := createTestElection(t, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))
+	candidateId := election.Candidates[0].ID.String()
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	w := uploadImage(r, candidateId, png)
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload responded %d %q", w.Code, w.Body.String())
+	}
+	var candidate database.Candidate
+	if err := json.Unmarshal(w.Body.Bytes(), &candidate); err != nil {
+		t.Fatal(err)
+	}
+
+	w = request(r, "GET", "/assets/"+candidate.Image, nil)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), png) {
+		t.Fatalf("asset responded %d %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", got)
+	}
+	if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+	}
+
+	if w := uploadImage(r, candidateId, []byte("<svg><script>alert(1)</script></svg>")); w.Code != http.StatusBadRequest {
+		t.Errorf("svg upload responded %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	for _, name := range []string{"..%2F.env", "missing.png", candidate.Image + ".html"} {
+		if w := request(r, "GET", "/assets/"+name, nil); w.Code != http.StatusNotFound {
+			t.Errorf("asset %q responded %d, want %d", name, w.Code, http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"durn/server/service"
+	"durn/server/util"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// ImportElections creates the elections and candidates described by the
+// request body, which is JSON if the content type is application/json and
+// YAML otherwise. Nothing is created if any item is invalid, and all
+// problems are returned as a list of items and errors.
+// Responds with the ids of the created elections
+func ImportElections(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadParametersMessage)
+		return
+	}
+	if c.ContentType() != "application/json" {
+		if data, err = util.YamlToJson(data); err != nil {
+			fmt.Println(err)
+			c.String(http.StatusBadRequest, "Malformed import document: "+err.Error())
+			return
+		}
+	}
+
+	doc, err := service.ParseImport(data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	elections, err := electionService.Import(doc)
+	var importErrors service.ImportErrors
+	if errors.As(err, &importErrors) {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": importErrors})
+		return
+	} else if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ids := []uuid.UUID{}
+	for _, election := range elections {
+		ids = append(ids, election.ID)
+		publishElectionEvent(service.EventElectionCreated, election)
+	}
+	c.JSON(http.StatusOK, ids)
+}
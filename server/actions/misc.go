@@ -4,36 +4,22 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"durn/server/util"
 
-	database "durn/server/db"
+	"github.com/gin-gonic/gin"
 )
 
-func NukeElections(c *gin.Context) {
-	db := database.GetDB()
+// requestLocale picks the locale of the response from the lang query
+// parameter or the Accept-Language header, and sets the Content-Language
+func requestLocale(c *gin.Context) string {
+	locale := util.MatchLocale(c.Query("lang"), c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", locale)
+	c.Header("Vary", "Accept-Language")
+	return locale
+}
 
-	if err := db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("1=1").Delete(&database.Ranking{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("1=1").Delete(&database.Vote{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("1=1").Delete(&database.VoteHash{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("1=1").Delete(&database.Candidate{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("1=1").Delete(&database.CastedVote{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("1=1").Delete(&database.Election{}).Error; err != nil {
-			return err
-		}
-		return nil
-	}); err != nil {
+func NukeElections(c *gin.Context) {
+	if err := electionService.DeleteAll(); err != nil {
 		fmt.Println(err)
 		c.String(http.StatusInternalServerError, "Deletion of tables failed")
 		return
@@ -0,0 +1,69 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"durn/server/service"
+	"durn/server/util"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// GetProxies fetches all proxies in the registry
+func GetProxies(c *gin.Context) {
+	proxies, err := proxyService.List()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, proxies)
+}
+
+// AddProxy registers that the grantor lets the holder vote on their behalf
+// between validFrom and validUntil, in the election or in all elections if
+// none is specified
+func AddProxy(c *gin.Context) {
+	body := service.ProxyParams{}
+	if err := c.BindJSON(&body); err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadParametersMessage)
+		return
+	}
+
+	proxy, err := proxyService.Add(body)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, proxy)
+}
+
+// RemoveProxy removes the specified proxy from the registry
+func RemoveProxy(c *gin.Context) {
+	proxyId, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadUUIDMessage)
+		return
+	}
+
+	if err := proxyService.Remove(proxyId); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.String(http.StatusOK, "")
+}
+
+// GetHeldProxies fetches the proxies the logged in user currently holds, so
+// that they know whom they can vote for
+func GetHeldProxies(c *gin.Context) {
+	proxies, err := proxyService.Held(c.GetString("user"), time.Now())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, proxies)
+}
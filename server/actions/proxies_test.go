@@ -0,0 +1,64 @@
+package actions
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"durn/server/service"
+)
+
+func TestCastVoteOnBehalfOf(t *testing.T) {
+	r := newTestRouter(t, "holder@kth.se")
+	now := time.Now()
+	election := createTestElection(t, now.Add(-time.Hour), now.Add(time.Hour))
+	path := "/election/" + election.ID.String()
+	if err := voterService.Add([]string{"holder@kth.se", "grantor@kth.se", "other@kth.se"}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := proxyService.Add(service.ProxyParams{
+		Grantor:    "grantor@kth.se",
+		Holder:     "holder@kth.se",
+		ElectionID: &election.ID,
+		ValidFrom:  now.Add(-time.Hour),
+		ValidUntil: now.Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ranking := rankingOf(election)
+	for _, body := range []map[string]any{
+		{"ranking": ranking},
+		{"ranking": ranking, "onBehalfOf": "grantor@kth.se"},
+	} {
+		if w := request(r, "POST", path+"/vote", body); w.Code != http.StatusOK {
+			t.Fatalf("vote with %v responded %d %q", body, w.Code, w.Body.String())
+		}
+	}
+	w := request(r, "GET", path+"/vote-count", nil)
+	if w.Body.String() != "2" {
+		t.Errorf("vote-count responded %q, want 2", w.Body.String())
+	}
+	w = request(r, "GET", path+"/has-voted?onBehalfOf=grantor@kth.se", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "true" {
+		t.Errorf("has-voted for the grantor responded %d %q, want true", w.Code, w.Body.String())
+	}
+
+	// Once the grantor has voted themselves, the proxy can't be used
+	if _, err := voteService.Cast(election.ID, "grantor@kth.se", "", ranking, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	w = request(r, "POST", path+"/vote", map[string]any{"ranking": ranking, "onBehalfOf": "grantor@kth.se"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("vote after the grantor voted responded %d %q, want %d", w.Code, w.Body.String(), http.StatusBadRequest)
+	}
+
+	w = request(r, "POST", path+"/vote", map[string]any{"ranking": ranking, "onBehalfOf": "other@kth.se"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("vote without a proxy responded %d %q, want %d", w.Code, w.Body.String(), http.StatusBadRequest)
+	}
+	w = request(r, "GET", path+"/has-voted?onBehalfOf=other@kth.se", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("has-voted without a proxy responded %d %q, want %d", w.Code, w.Body.String(), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,34 @@
+package actions
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"durn/server/service"
+	"durn/server/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReceiptKey returns the base64 encoded Ed25519 public key that receipts
+// are signed with, so that they can be verified without the server
+func GetReceiptKey(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"algorithm": "ed25519",
+		"publicKey": base64.StdEncoding.EncodeToString(receiptSigner.PublicKey()),
+	})
+}
+
+// VerifyReceipt checks the signature of a receipt given by CastVote. Public,
+// so that voters can prove that their vote was accepted
+func VerifyReceipt(c *gin.Context) {
+	var receipt service.Receipt
+	if err := c.BindJSON(&receipt); err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadParametersMessage)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valid": receiptSigner.Verify(receipt)})
+}
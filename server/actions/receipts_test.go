@@ -0,0 +1,79 @@
+package actions
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"durn/config"
+	"durn/server/service"
+	"durn/server/service/servicetest"
+)
+
+func TestVoteReceipt(t *testing.T) {
+	r := newTestRouter(t, "voter@kth.se")
+	r.GET("/receipts/key", GetReceiptKey)
+	r.POST("/receipts/verify", VerifyReceipt)
+	election := createTestElection(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	w := request(r, "POST", "/election/"+election.ID.String()+"/vote", map[string]any{
+		"ranking": rankingOf(election),
+		"secret":  "hemligt",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("vote responded %d %q", w.Code, w.Body.String())
+	}
+	var receipt service.Receipt
+	if err := json.Unmarshal(w.Body.Bytes(), &receipt); err != nil {
+		t.Fatal(err)
+	}
+	want := service.NewReceipt(election.ID, "voter@kth.se", "hemligt", rankingOf(election), receipt.Time)
+	if receipt.Election != election.ID || receipt.Commitment != want.Commitment {
+		t.Errorf("receipt = %+v, want commitment %s", receipt, want.Commitment)
+	}
+
+	verify := func(receipt service.Receipt) bool {
+		t.Helper()
+		w := request(r, "POST", "/receipts/verify", receipt)
+		var body struct{ Valid bool }
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("verify responded %d %q", w.Code, w.Body.String())
+		}
+		return body.Valid
+	}
+	if !verify(receipt) {
+		t.Error("receipt from the vote did not verify")
+	}
+	forged := receipt
+	forged.Commitment = want.Commitment[1:] + "0"
+	if verify(forged) {
+		t.Error("forged receipt verified")
+	}
+
+	// The receipt can also be verified offline with the public key
+	w = request(r, "GET", "/receipts/key", nil)
+	var key struct{ PublicKey string }
+	if err := json.Unmarshal(w.Body.Bytes(), &key); err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !service.VerifyReceipt(ed25519.PublicKey(publicKey), receipt) {
+		t.Error("receipt did not verify with the published key")
+	}
+
+	if w := request(r, "POST", "/receipts/verify", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("verify without a receipt responded %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestReceiptKeyRequired(t *testing.T) {
+	if err := Init(servicetest.NewRepository(t), &config.Config{BLOB_DIR: t.TempDir()}); err == nil {
+		t.Error("Init succeeded without RECEIPT_KEY")
+	}
+}
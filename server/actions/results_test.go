@@ -0,0 +1,68 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"durn/server/service"
+)
+
+func TestValidityReported(t *testing.T) {
+	r := newTestRouter(t, "voter@kth.se")
+	r.PUT("/election/:id/finalize", FinalizeElection)
+	r.GET("/election/:id/validity", GetValidity)
+	r.GET("/election/:id/count", CountVotesSchultze)
+	r.GET("/election/:id/count-irv", CountVotes)
+
+	election := createTestElection(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	quorum, nonBlank := 2, 0.5
+	if _, err := electionService.Edit(election.ID, service.ElectionChanges{MinTurnout: &quorum, MinNonBlankShare: &nonBlank}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := voteService.Cast(election.ID, "voter@kth.se", "", rankingOf(election), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Finalizing ends voting, so the quorum is checked also before the close
+	// time, while the share of non-blank votes and the count wait until then
+	path := "/election/" + election.ID.String()
+	checkValidity := func(method string, route string, closed bool) {
+		t.Helper()
+		w := request(r, method, route, nil)
+		var body struct {
+			Validity *service.Validity `json:"validity"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s responded %d %q", route, w.Code, w.Body.String())
+		}
+		if body.Validity == nil || body.Validity.Valid || len(body.Validity.Reasons) != 1 {
+			t.Errorf("%s validity = %+v, want void below the quorum", route, body.Validity)
+		}
+		if body.Validity != nil && (body.Validity.NonBlankShare != nil) != closed {
+			t.Errorf("%s share of non-blank votes = %v, want it shown %v", route, body.Validity.NonBlankShare, closed)
+		}
+		if body.Validity != nil && (len(body.Validity.Pending) == 0) != closed {
+			t.Errorf("%s pending rules = %v, want them pending %v", route, body.Validity.Pending, !closed)
+		}
+	}
+	checkValidity("PUT", path+"/finalize", false)
+	var validity service.Validity
+	w := request(r, "GET", path+"/validity", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &validity); err != nil || validity.Valid || validity.Turnout != 1 || validity.NonBlankShare != nil {
+		t.Errorf("validity responded %d %q, want void with a turnout of 1", w.Code, w.Body.String())
+	}
+	if w := request(r, "GET", path+"/count", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("count before close responded %d %q, want %d", w.Code, w.Body.String(), http.StatusBadRequest)
+	}
+
+	closeTestElection(t, election)
+	checkValidity("GET", path+"/count", true)
+	// The IRV count is still a list of the stages
+	var stages []service.IRVStage
+	w = request(r, "GET", path+"/count-irv", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &stages); err != nil || len(stages) == 0 {
+		t.Errorf("count-irv responded %d %q, want a list of stages", w.Code, w.Body.String())
+	}
+}
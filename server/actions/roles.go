@@ -0,0 +1,58 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+
+	"durn/server/service"
+	"durn/server/util"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// GetRoles fetches all roles in the local role store
+func GetRoles(c *gin.Context) {
+	roles, err := roleService.List()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// AddRole grants a permission to a user in the local role store. If an
+// election is specified the permission is only valid for that election.
+// Allowed permissions are admin-read, admin-write and observer, where
+// observer requires an election.
+func AddRole(c *gin.Context) {
+	body := service.RoleParams{}
+	if err := c.BindJSON(&body); err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadParametersMessage)
+		return
+	}
+
+	role, err := roleService.Add(body)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// RemoveRole removes the specified role from the local role store
+func RemoveRole(c *gin.Context) {
+	roleId, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		fmt.Println(err)
+		c.String(http.StatusBadRequest, util.BadUUIDMessage)
+		return
+	}
+
+	if err := roleService.Remove(roleId); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.String(http.StatusOK, "")
+}
@@ -0,0 +1,114 @@
+package actions
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"durn/config"
+	"durn/server/service"
+	"durn/server/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Services used by the handlers, set up by Init
+var (
+	electionService     *service.Elections
+	voteService         *service.Votes
+	voterService        *service.Voters
+	roleService         *service.Roles
+	imageService        *service.Images
+	eventService        *service.Events
+	notificationService *service.Notifications
+	receiptSigner       *service.ReceiptSigner
+	proxyService        *service.Proxies
+)
+
+// Init sets up the services used by the handlers with the given repository
+// and config
+func Init(repo service.Repository, conf *config.Config) error {
+	blobs, err := service.NewDirBlobStore(conf.BLOB_DIR)
+	if err != nil {
+		return err
+	}
+
+	electionService = service.NewElections(repo)
+	voteService = service.NewVotes(repo)
+	voterService = service.NewVoters(repo)
+	roleService = service.NewRoles(repo)
+	proxyService = service.NewProxies(repo, conf.MAX_PROXIES)
+	imageService = service.NewImages(repo, blobs, int64(conf.MAX_IMAGE_SIZE))
+	eventService = service.NewEvents()
+
+	receiptKey, err := loadReceiptKey(conf)
+	if err != nil {
+		return err
+	}
+	receiptSigner = service.NewReceiptSigner(receiptKey)
+
+	mailer, err := newMailer(conf)
+	if err != nil {
+		return err
+	}
+	notificationService = service.NewNotifications(repo, mailer, service.NotificationOptions{
+		PublicURL:      conf.PUBLIC_URL,
+		Interval:       conf.MAIL_INTERVAL,
+		ReminderBefore: conf.REMINDER_BEFORE_CLOSE,
+	})
+	return nil
+}
+
+// newMailer creates the mailer specified by MAILER, or nil if mails are
+// disabled
+func newMailer(conf *config.Config) (service.Mailer, error) {
+	switch conf.MAILER {
+	case "", "none":
+		return nil, nil
+	case "stdout":
+		return service.NewWriterMailer(os.Stdout, conf.MAIL_FROM), nil
+	case "file":
+		f, err := os.OpenFile(conf.MAIL_FILE, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		return service.NewWriterMailer(f, conf.MAIL_FROM), nil
+	case "smtp":
+		mailer, err := service.NewSMTPMailer(conf.SMTP_HOST, conf.SMTP_PORT, conf.SMTP_USERNAME, conf.SMTP_PASSWORD, conf.MAIL_FROM)
+		if err != nil {
+			return nil, err
+		}
+		return mailer, nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER '%s', use none, stdout, file or smtp", conf.MAILER)
+	}
+}
+
+// loadReceiptKey parses RECEIPT_KEY. It is required, since receipts signed
+// with a temporary key could not be verified after a restart
+func loadReceiptKey(conf *config.Config) (ed25519.PrivateKey, error) {
+	if conf.RECEIPT_KEY == "" {
+		return nil, errors.New("RECEIPT_KEY is not set, generate one with 'durn receipts keygen'")
+	}
+	return service.ParseReceiptKey(conf.RECEIPT_KEY)
+}
+
+// RunNotifications sends notifications to voters, checking for due
+// notifications every interval. Returns immediately if mails are disabled
+func RunNotifications(interval time.Duration) {
+	notificationService.Run(interval)
+}
+
+// respondError responds with the message of errors caused by invalid input,
+// and with a generic message for all other errors
+func respondError(c *gin.Context, err error) {
+	fmt.Println(err)
+	if errors.Is(err, service.ErrInvalid) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	c.String(http.StatusInternalServerError, util.RequestFailedMessage)
+}
@@ -4,20 +4,22 @@ import (
 	"fmt"
 	"net/http"
 
-	database "durn/server/db"
 	"durn/server/util"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 // AddVoters takes a list of email addresses and adds them all to the
-// database table `valid_voters`. It silently skips all strings that are
-// not valid email addresses and addresses that are already in the database
+// database table `valid_voters`, with the locale they get notifications in
+// and the optional weight of their votes.
+// It silently skips all strings that are not valid email addresses, and
+// only updates the locale of addresses that are already in the database, and
+// their weight if one is given
 func AddVoters(c *gin.Context) {
 	body := struct {
 		Voters []string `json:"voters" binding:"required"`
+		Locale string   `json:"locale"`
+		Weight *int     `json:"weight"`
 	}{}
 
 	if err := c.BindJSON(&body); err != nil {
@@ -26,29 +28,12 @@ func AddVoters(c *gin.Context) {
 		return
 	}
 
-	var voters []database.ValidVoter
-	for _, voter := range body.Voters {
-		if util.ValidEmail(voter) {
-			voters = append(voters, database.ValidVoter{Email: voter})
-		}
-	}
-
-	db := database.GetDB()
-	defer database.ReleaseDB()
-
-	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&voters).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
+	if err := voterService.Add(body.Voters, body.Locale, body.Weight); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	result, err := getAllVoters(db)
-	if err != nil {
-		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
-		return
-	}
-	c.JSON(http.StatusOK, result)
+	respondWithAllVoters(c)
 }
 
 // RemoveVoters takes a list of email addresses and removes them from the database.
@@ -64,41 +49,17 @@ func RemoveVoters(c *gin.Context) {
 		return
 	}
 
-	var voters []database.ValidVoter
-	for _, voter := range body.Voters {
-		voters = append(voters, database.ValidVoter{Email: voter})
-	}
-
-	db := database.GetDB()
-	defer database.ReleaseDB()
-
-	if err := db.Delete(&voters).Error; err != nil {
-		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
+	if err := voterService.Remove(body.Voters); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	result, err := getAllVoters(db)
-	if err != nil {
-		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
-		return
-	}
-	c.JSON(http.StatusOK, result)
+	respondWithAllVoters(c)
 }
 
 // GetVoters fetches all current allowed voters from the database
 func GetVoters(c *gin.Context) {
-	db := database.GetDB()
-	defer database.ReleaseDB()
-
-	result, err := getAllVoters(db)
-	if err != nil {
-		fmt.Println(err)
-		c.String(http.StatusInternalServerError, util.RequestFailedMessage)
-		return
-	}
-	c.JSON(http.StatusOK, result)
+	respondWithAllVoters(c)
 }
 
 // UserAllowedToVote checks if the logged in voter is allowed to vote.
@@ -107,20 +68,24 @@ func UserAllowedToVote(c *gin.Context) {
 	c.JSON(http.StatusOK, "true")
 }
 
+// votersResponse lists the voters, and the weights of those whose votes don't
+// have weight 1
 type votersResponse struct {
-	Voters []string `json:"voters"`
+	Voters  []string       `json:"voters"`
+	Weights map[string]int `json:"weights,omitempty"`
 }
 
-// getAllVoters fetches all current voters from the database and returns them in a response ready way
-func getAllVoters(db *gorm.DB) (votersResponse, error) {
-	var result votersResponse
-	var voters []database.ValidVoter
-	if err := db.Find(&voters).Error; err != nil {
-		return result, err
+// respondWithAllVoters fetches all current voters and responds with them
+func respondWithAllVoters(c *gin.Context) {
+	voters, err := voterService.List()
+	if err != nil {
+		respondError(c, err)
+		return
 	}
-	result.Voters = []string{}
-	for _, voter := range voters {
-		result.Voters = append(result.Voters, voter.Email)
+	weights, err := voterService.Weights()
+	if err != nil {
+		respondError(c, err)
+		return
 	}
-	return result, nil
+	c.JSON(http.StatusOK, votersResponse{Voters: voters, Weights: weights})
 }
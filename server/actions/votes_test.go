@@ -0,0 +1,194 @@
+package actions
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"durn/config"
+	database "durn/server/db"
+	"durn/server/service"
+	"durn/server/service/servicetest"
+	"durn/server/util"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// testRepo is the repository of the latest router from newTestRouter
+var testRepo service.Repository
+
+// newTestRouter sets up the handlers with an empty database, and returns a
+// router with the voting routes where requests are made as the given user
+func newTestRouter(t *testing.T, user string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	testRepo = servicetest.NewRepository(t)
+	if err := Init(testRepo, &config.Config{
+		BLOB_DIR:       t.TempDir(),
+		MAX_IMAGE_SIZE: 1024,
+		RECEIPT_KEY:    "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+		MAX_PROXIES:    1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", user)
+	})
+	r.POST("/election/:id/vote", CastVote)
+	r.GET("/election/:id/has-voted", HasVoted)
+	r.GET("/election/:id/votes", GetVotes)
+	r.GET("/election/:id/vote-count", GetVoteCount)
+	return r
+}
+
+// createTestElection creates an election with the given voting interval and
+// two candidates, and returns it with all its candidates
+func createTestElection(t *testing.T, open time.Time, close time.Time) database.Election {
+	t.Helper()
+	params := service.DefaultElectionParams()
+	params.Name = "Kassör"
+	params.OpenTime = util.NullTime{Time: open, Valid: true}
+	params.CloseTime = util.NullTime{Time: close, Valid: true}
+	election, err := electionService.Create(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Alice", "Bob"} {
+		if _, err := electionService.AddCandidate(election.ID, service.CandidateParams{Name: name}, open.Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	election, err = electionService.Get(election.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return election
+}
+
+// closeTestElection moves the close time of the election to the past, so that
+// its ballots and results can be seen. Also works for finalized elections
+func closeTestElection(t *testing.T, election database.Election) {
+	t.Helper()
+	election, err := electionService.Get(election.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	election.CloseTime = sql.NullTime{Time: time.Now().Add(-time.Second), Valid: true}
+	if err := testRepo.SaveElection(&election); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func rankingOf(election database.Election) []uuid.UUID {
+	var ranking []uuid.UUID
+	for _, candidate := range election.Candidates {
+		ranking = append(ranking, candidate.ID)
+	}
+	return ranking
+}
+
+func request(r *gin.Engine, method string, path string, body any) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, &reader))
+	return w
+}
+
+func TestCastVoteWindow(t *testing.T) {
+	r := newTestRouter(t, "voter@kth.se")
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		open   time.Time
+		close  time.Time
+		status int
+	}{
+		{"not opened", now.Add(time.Hour), now.Add(2 * time.Hour), http.StatusBadRequest},
+		{"open", now.Add(-time.Hour), now.Add(time.Hour), http.StatusOK},
+		{"closed", now.Add(-2 * time.Hour), now.Add(-time.Hour), http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			election := createTestElection(t, test.open, test.close)
+			w := request(r, "POST", "/election/"+election.ID.String()+"/vote", map[string]any{
+				"ranking": rankingOf(election),
+			})
+			if w.Code != test.status {
+				t.Errorf("vote responded %d %q, want %d", w.Code, w.Body.String(), test.status)
+			}
+
+			want := "false"
+			if test.status == http.StatusOK {
+				want = "true"
+			}
+			w = request(r, "GET", "/election/"+election.ID.String()+"/has-voted", nil)
+			if w.Body.String() != want {
+				t.Errorf("has-voted responded %q, want %q", w.Body.String(), want)
+			}
+		})
+	}
+}
+
+func TestCastVoteReplaces(t *testing.T) {
+	r := newTestRouter(t, "voter@kth.se")
+	election := createTestElection(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	path := "/election/" + election.ID.String()
+
+	ranking := rankingOf(election)
+	reversed := []uuid.UUID{ranking[2], ranking[1], ranking[0]}
+	for _, vote := range [][]uuid.UUID{ranking, reversed} {
+		if w := request(r, "POST", path+"/vote", map[string]any{"ranking": vote}); w.Code != http.StatusOK {
+			t.Fatalf("vote responded %d %q", w.Code, w.Body.String())
+		}
+	}
+
+	w := request(r, "GET", path+"/vote-count", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "1" {
+		t.Errorf("vote-count responded %d %q, want 1", w.Code, w.Body.String())
+	}
+
+	closeTestElection(t, election)
+	w = request(r, "GET", path+"/votes", nil)
+	var votes []service.BallotRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &votes); err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 1 || len(votes[0].Rankings) != 3 || votes[0].Rankings[0] != reversed[0] {
+		t.Errorf("votes = %+v, want a single vote ranked %v", votes, reversed)
+	}
+}
+
+func TestCastVoteInvalid(t *testing.T) {
+	r := newTestRouter(t, "voter@kth.se")
+	election := createTestElection(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	ranking := rankingOf(election)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   any
+		status int
+	}{
+		{"malformed id", "/election/abc/vote", map[string]any{"ranking": ranking}, http.StatusBadRequest},
+		{"missing ranking", "/election/" + election.ID.String() + "/vote", map[string]any{}, http.StatusBadRequest},
+		{"missing candidate", "/election/" + election.ID.String() + "/vote", map[string]any{"ranking": ranking[:2]}, http.StatusBadRequest},
+		{"unknown election", "/election/" + uuid.NewV4().String() + "/vote", map[string]any{"ranking": ranking}, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if w := request(r, "POST", test.path, test.body); w.Code != test.status {
+				t.Errorf("responded %d %q, want %d", w.Code, w.Body.String(), test.status)
+			}
+		})
+	}
+}
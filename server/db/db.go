@@ -1,8 +1,8 @@
 package db
 
 import (
+	"errors"
 	"fmt"
-	"os"
 
 	// "sync"
 
@@ -10,28 +10,48 @@ import (
 	"gorm.io/gorm"
 
 	"durn/config"
+	"durn/server/util"
 )
 
 var db *gorm.DB
 
 // var m sync.Mutex
 
-func InitDB() {
+// InitDB connects to the database and checks that the schema has the version
+// expected by the code. Migrations are not applied automatically, they are
+// applied with `durn migrate up`
+func InitDB() error {
+	if err := Connect(); err != nil {
+		return err
+	}
+	return CheckSchema(db)
+}
+
+// Connect connects to the database, retrying with backoff if it can not be reached
+func Connect() error {
 	c := config.GetConfig()
 	dsn := c.DATABASE_URL
-	var err error
-	if db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{}); err != nil {
-		fmt.Println(err)
-		os.Exit(5)
+	if err := util.Retry(c.STARTUP_RETRIES, c.STARTUP_RETRY_DELAY, func() error {
+		var err error
+		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	db.AutoMigrate(&Election{})
-	db.AutoMigrate(&ValidVoter{})
-	db.AutoMigrate(&Candidate{})
-	db.AutoMigrate(&Vote{})
-	db.AutoMigrate(&Ranking{})
-	db.AutoMigrate(&CastedVote{})
-	db.AutoMigrate(&VoteHash{})
+	return nil
+}
+
+// Ping checks that the database can be reached
+func Ping() error {
+	if db == nil {
+		return errors.New("database is not initialized")
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Ping()
 }
 
 func GetDB() *gorm.DB {
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrations are stored as pairs of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, where versions are consecutive numbers starting at 1.
+// Migrations that have been released must never be changed, add a new
+// migration instead.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var (
+	ErrSchemaBehind = errors.New("database schema is behind the code, run `durn migrate up`")
+	ErrSchemaAhead  = errors.New("database schema is ahead of the code, deploy a newer version or run `durn migrate down` with a newer version")
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// schemaMigration is a row in the table keeping track of applied migrations
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrations returns all migrations embedded in the binary, ordered by version
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name '%s'", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("missing migration with version %d", i+1)
+		}
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d is missing an up or down file", m.Version)
+		}
+	}
+	return migrations, nil
+}
+
+// LatestVersion returns the schema version expected by the code
+func LatestVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	return len(migrations), nil
+}
+
+// SchemaVersion returns the version of the latest migration applied to the
+// database, or 0 if no migrations have been applied
+func SchemaVersion(db *gorm.DB) (int, error) {
+	if err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL
+	)`).Error; err != nil {
+		return 0, err
+	}
+	var version int
+	if err := db.Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// CheckSchema returns an error if the database schema does not have the
+// version expected by the code
+func CheckSchema(db *gorm.DB) error {
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	if current < latest {
+		return fmt.Errorf("%w (at version %d, expected %d)", ErrSchemaBehind, current, latest)
+	}
+	if current > latest {
+		return fmt.Errorf("%w (at version %d, expected %d)", ErrSchemaAhead, current, latest)
+	}
+	return nil
+}
+
+// MigrateUp applies the given amount of migrations that have not been
+// applied yet, or all of them if steps is 0 or less. Each migration is
+// applied in its own transaction. Returns the resulting schema version
+func MigrateUp(db *gorm.DB, steps int) (int, error) {
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return current, err
+	}
+	if current > len(migrations) {
+		return current, fmt.Errorf("%w (at version %d, expected %d)", ErrSchemaAhead, current, len(migrations))
+	}
+
+	pending := migrations[current:]
+	if steps > 0 && steps < len(pending) {
+		pending = pending[:steps]
+	}
+	for _, m := range pending {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		}); err != nil {
+			return current, fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		current = m.Version
+		fmt.Printf("Applied migration %d_%s\n", m.Version, m.Name)
+	}
+	return current, nil
+}
+
+// MigrateDown reverts the given amount of applied migrations, latest first.
+// Returns the resulting schema version
+func MigrateDown(db *gorm.DB, steps int) (int, error) {
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return current, err
+	}
+	if current > len(migrations) {
+		return current, fmt.Errorf("%w (at version %d, expected %d)", ErrSchemaAhead, current, len(migrations))
+	}
+
+	for ; steps > 0 && current > 0; steps-- {
+		m := migrations[current-1]
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{Version: m.Version}).Error
+		}); err != nil {
+			return current, fmt.Errorf("reverting migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		current = m.Version - 1
+		fmt.Printf("Reverted migration %d_%s\n", m.Version, m.Name)
+	}
+	return current, nil
+}
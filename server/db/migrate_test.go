@@ -0,0 +1,119 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB returns an empty in-memory SQLite database
+func newTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// column is what the models and migrations must agree on for a column. The
+// types are left out, since the migrations use postgres types
+type column struct {
+	nullable     bool
+	defaultValue string
+}
+
+// columnsOf returns the columns of the table of each model
+func columnsOf(t *testing.T, db *gorm.DB) map[string]map[string]column {
+	t.Helper()
+	tables := map[string]map[string]column{}
+	for _, model := range Models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		var info []struct {
+			Name      string
+			NotNull   bool `gorm:"column:notnull"`
+			DfltValue *string
+		}
+		if err := db.Raw("SELECT name, \"notnull\", dflt_value FROM pragma_table_info(?)", stmt.Schema.Table).Scan(&info).Error; err != nil {
+			t.Fatal(err)
+		}
+		columns := map[string]column{}
+		for _, c := range info {
+			columns[c.Name] = column{nullable: !c.NotNull}
+			if c.DfltValue != nil {
+				columns[c.Name] = column{nullable: !c.NotNull, defaultValue: normalizeDefault(*c.DfltValue)}
+			}
+		}
+		tables[stmt.Schema.Table] = columns
+	}
+	return tables
+}
+
+// normalizeDefault removes the quotes around strings and formats numbers
+// the same way, since gorm and the migrations write them differently
+func normalizeDefault(value string) string {
+	value = strings.Trim(value, `'"`)
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return strconv.FormatFloat(number, 'g', -1, 64)
+	}
+	return value
+}
+
+func TestMigrationsMatchModels(t *testing.T) {
+	migrated := newTestDB(t, "migrated")
+	if _, err := MigrateUp(migrated, 0); err != nil {
+		t.Fatal(err)
+	}
+	models := newTestDB(t, "models")
+	if err := models.AutoMigrate(Models...); err != nil {
+		t.Fatal(err)
+	}
+
+	want := columnsOf(t, models)
+	got := columnsOf(t, migrated)
+	for table, columns := range want {
+		for name, c := range columns {
+			if m, ok := got[table][name]; !ok {
+				t.Errorf("column %s.%s is missing from the migrations", table, name)
+			} else if m != c {
+				t.Errorf("column %s.%s is %+v in the migrations, want %+v as in the models", table, name, m, c)
+			}
+		}
+		for name := range got[table] {
+			if _, ok := columns[name]; !ok {
+				t.Errorf("column %s.%s from the migrations is not in the models", table, name)
+			}
+		}
+	}
+}
+
+func TestMigrationsDown(t *testing.T) {
+	db := newTestDB(t, "down")
+	latest, err := MigrateUp(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, err := MigrateDown(db, latest); err != nil || version != 0 {
+		t.Fatalf("MigrateDown() = %d, %v, want 0", version, err)
+	}
+	for _, model := range Models {
+		if db.Migrator().HasTable(model) {
+			t.Errorf("table of %T is left after reverting all migrations", model)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+DROP TABLE roles;
+DROP TABLE vote_hashes;
+DROP TABLE casted_votes;
+DROP TABLE rankings;
+DROP TABLE votes;
+DROP TABLE candidates;
+DROP TABLE valid_voters;
+DROP TABLE elections;
@@ -0,0 +1,64 @@
+-- Tables as previously created by gorm's AutoMigrate. IF NOT EXISTS is used so
+-- that databases created before migrations were introduced can be adopted
+
+CREATE TABLE IF NOT EXISTS elections (
+    id text PRIMARY KEY,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    published boolean NOT NULL,
+    finalized boolean NOT NULL,
+    mandates bigint NOT NULL DEFAULT 1,
+    extra_mandates bigint NOT NULL DEFAULT 0,
+    open_time timestamptz,
+    close_time timestamptz,
+    deleted timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS valid_voters (
+    email text PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS candidates (
+    id text PRIMARY KEY,
+    name text NOT NULL,
+    presentation text NOT NULL,
+    election_id text NOT NULL,
+    symbolic boolean NOT NULL DEFAULT false,
+    deleted timestamptz,
+    CONSTRAINT fk_elections_candidates FOREIGN KEY (election_id) REFERENCES elections (id)
+);
+
+CREATE TABLE IF NOT EXISTS votes (
+    id text PRIMARY KEY,
+    vote_time timestamptz NOT NULL,
+    election_id text NOT NULL,
+    user_hash text
+);
+
+CREATE TABLE IF NOT EXISTS rankings (
+    vote_id text,
+    rank bigint,
+    candidate_id text NOT NULL,
+    PRIMARY KEY (vote_id, rank),
+    CONSTRAINT fk_votes_rankings FOREIGN KEY (vote_id) REFERENCES votes (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS casted_votes (
+    email text,
+    election_id text,
+    PRIMARY KEY (email, election_id)
+);
+
+CREATE TABLE IF NOT EXISTS vote_hashes (
+    hash text NOT NULL,
+    election_id text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+    id text PRIMARY KEY,
+    email text NOT NULL,
+    permission text NOT NULL,
+    election_id text
+);
+
+CREATE INDEX IF NOT EXISTS idx_roles_email ON roles (email);
@@ -0,0 +1,2 @@
+ALTER TABLE elections DROP COLUMN shuffle_candidates;
+ALTER TABLE candidates DROP COLUMN position;
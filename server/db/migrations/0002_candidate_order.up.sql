@@ -0,0 +1,11 @@
+ALTER TABLE candidates ADD COLUMN position bigint NOT NULL DEFAULT 0;
+ALTER TABLE elections ADD COLUMN shuffle_candidates boolean NOT NULL DEFAULT false;
+
+-- Keep the order existing candidates were shown in, by name
+UPDATE candidates SET position = ordered.position
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY election_id ORDER BY name) AS position
+    FROM candidates
+    WHERE NOT symbolic
+) AS ordered
+WHERE candidates.id = ordered.id;
@@ -0,0 +1 @@
+ALTER TABLE candidates DROP COLUMN withdrawn;
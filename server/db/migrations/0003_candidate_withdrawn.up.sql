@@ -0,0 +1 @@
+ALTER TABLE candidates ADD COLUMN withdrawn boolean NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+ALTER TABLE candidates DROP COLUMN links;
+ALTER TABLE candidates DROP COLUMN image;
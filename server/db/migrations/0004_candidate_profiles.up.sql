@@ -0,0 +1,2 @@
+ALTER TABLE candidates ADD COLUMN image text NOT NULL DEFAULT '';
+ALTER TABLE candidates ADD COLUMN links text NOT NULL DEFAULT '[]';
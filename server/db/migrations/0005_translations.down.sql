@@ -0,0 +1,2 @@
+ALTER TABLE candidates DROP COLUMN translations;
+ALTER TABLE elections DROP COLUMN translations;
@@ -0,0 +1,2 @@
+ALTER TABLE elections ADD COLUMN translations text NOT NULL DEFAULT '{}';
+ALTER TABLE candidates ADD COLUMN translations text NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+ALTER TABLE candidates ADD COLUMN symbolic_kind text NOT NULL DEFAULT '';
+
+UPDATE candidates SET symbolic_kind = 'vacant', position = 1 WHERE symbolic AND name = 'Vakant';
+UPDATE candidates SET symbolic_kind = 'blank', position = 3 WHERE symbolic AND name = 'Blank';
@@ -0,0 +1,3 @@
+DROP TABLE sent_notifications;
+
+ALTER TABLE valid_voters DROP COLUMN locale;
@@ -0,0 +1,9 @@
+ALTER TABLE valid_voters ADD COLUMN locale text NOT NULL DEFAULT '';
+
+CREATE TABLE sent_notifications (
+    email text,
+    election_id text,
+    kind text,
+    sent_at timestamptz NOT NULL,
+    PRIMARY KEY (email, election_id, kind)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE elections DROP COLUMN ballot_root;
+
+DROP INDEX idx_vote_hashes_vote_id;
+ALTER TABLE vote_hashes DROP COLUMN vote_id;
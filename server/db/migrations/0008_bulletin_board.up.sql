@@ -0,0 +1,4 @@
+ALTER TABLE vote_hashes ADD COLUMN vote_id text REFERENCES votes (id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX idx_vote_hashes_vote_id ON vote_hashes (vote_id);
+
+ALTER TABLE elections ADD COLUMN ballot_root text NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+DROP TABLE encrypted_ballots;
+
+ALTER TABLE elections DROP COLUMN key_threshold;
+ALTER TABLE elections DROP COLUMN encryption_key;
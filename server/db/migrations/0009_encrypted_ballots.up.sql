@@ -0,0 +1,9 @@
+ALTER TABLE elections ADD COLUMN encryption_key text NOT NULL DEFAULT '';
+ALTER TABLE elections ADD COLUMN key_threshold bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE encrypted_ballots (
+    vote_id text PRIMARY KEY REFERENCES votes (id) ON DELETE CASCADE,
+    election_id text NOT NULL,
+    ciphertext bytea NOT NULL
+);
+CREATE INDEX idx_encrypted_ballots_election_id ON encrypted_ballots (election_id);
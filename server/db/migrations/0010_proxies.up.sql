@@ -0,0 +1,10 @@
+CREATE TABLE proxies (
+    id text PRIMARY KEY,
+    grantor text NOT NULL,
+    holder text NOT NULL,
+    election_id text,
+    valid_from timestamptz NOT NULL,
+    valid_until timestamptz NOT NULL
+);
+CREATE INDEX idx_proxies_grantor ON proxies (grantor);
+CREATE INDEX idx_proxies_holder ON proxies (holder);
@@ -0,0 +1,2 @@
+ALTER TABLE votes DROP COLUMN weight;
+ALTER TABLE valid_voters DROP COLUMN weight;
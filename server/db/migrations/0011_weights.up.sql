@@ -0,0 +1,2 @@
+ALTER TABLE valid_voters ADD COLUMN weight bigint NOT NULL DEFAULT 1;
+ALTER TABLE votes ADD COLUMN weight bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+ALTER TABLE elections DROP COLUMN electorate;
+ALTER TABLE elections DROP COLUMN min_non_blank_share;
+ALTER TABLE elections DROP COLUMN min_turnout_share;
+ALTER TABLE elections DROP COLUMN min_turnout;
@@ -0,0 +1,4 @@
+ALTER TABLE elections ADD COLUMN min_turnout bigint NOT NULL DEFAULT 0;
+ALTER TABLE elections ADD COLUMN min_turnout_share double precision NOT NULL DEFAULT 0;
+ALTER TABLE elections ADD COLUMN min_non_blank_share double precision NOT NULL DEFAULT 0;
+ALTER TABLE elections ADD COLUMN electorate bigint NOT NULL DEFAULT 0;
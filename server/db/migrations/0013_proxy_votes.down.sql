@@ -0,0 +1 @@
+ALTER TABLE casted_votes DROP COLUMN proxy;
@@ -0,0 +1 @@
+ALTER TABLE casted_votes ADD COLUMN proxy text NOT NULL DEFAULT '';
@@ -2,64 +2,253 @@ package db
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"durn/server/util"
+
 	uuid "github.com/satori/go.uuid"
 	"gorm.io/gorm"
 )
 
+// Models are all models stored in the database, whose tables are created by
+// the migrations
+var Models = []any{
+	&Election{},
+	&ValidVoter{},
+	&Candidate{},
+	&Vote{},
+	&Ranking{},
+	&CastedVote{},
+	&VoteHash{},
+	&EncryptedBallot{},
+	&Proxy{},
+	&Role{},
+	&SentNotification{},
+}
+
+// Election is an election of one or more mandates. The Min fields are the
+// validity rules of the election, and Electorate is the size of the voter
+// roll when the election was finalized, which the turnout is compared to
 type Election struct {
-	ID            uuid.UUID      `gorm:"primaryKey" json:"id"`
-	Name          string         `gorm:"not null" json:"name"`
-	Description   string         `gorm:"not null;default:''" json:"description"`
-	Published     bool           `gorm:"not null" json:"published"`
-	Finalized     bool           `gorm:"not null" json:"finalized"`
-	Mandates      int            `gorm:"not null;default:1" json:"mandates"`
-	ExtraMandates int            `gorm:"not null;default:0" json:"extraMandates"`
-	OpenTime      sql.NullTime   `json:"openTime"`
-	CloseTime     sql.NullTime   `json:"closeTime"`
-	Candidates    []Candidate    `gorm:"foreignKey:ElectionID;references:ID" json:"candidates"`
-	Votes         []Vote         `json:"-"`
-	Deleted       gorm.DeletedAt `json:"-"`
+	ID                uuid.UUID      `gorm:"primaryKey" json:"id"`
+	Name              string         `gorm:"not null" json:"name"`
+	Description       string         `gorm:"not null;default:''" json:"description"`
+	Published         bool           `gorm:"not null" json:"published"`
+	Finalized         bool           `gorm:"not null" json:"finalized"`
+	Mandates          int            `gorm:"not null;default:1" json:"mandates"`
+	ExtraMandates     int            `gorm:"not null;default:0" json:"extraMandates"`
+	ShuffleCandidates bool           `gorm:"not null;default:false" json:"shuffleCandidates"`
+	Translations      Translations   `gorm:"type:text;not null;default:'{}'" json:"translations"`
+	OpenTime          sql.NullTime   `json:"openTime"`
+	CloseTime         sql.NullTime   `json:"closeTime"`
+	BallotRoot        string         `gorm:"not null;default:''" json:"ballotRoot"`
+	EncryptionKey     string         `gorm:"not null;default:''" json:"encryptionKey"`
+	KeyThreshold      int            `gorm:"not null;default:0" json:"keyThreshold"`
+	MinTurnout        int            `gorm:"not null;default:0" json:"minTurnout"`
+	MinTurnoutShare   float64        `gorm:"not null;default:0" json:"minTurnoutShare"`
+	MinNonBlankShare  float64        `gorm:"not null;default:0" json:"minNonBlankShare"`
+	Electorate        int            `gorm:"not null;default:0" json:"electorate"`
+	Candidates        []Candidate    `gorm:"foreignKey:ElectionID;references:ID" json:"candidates"`
+	Votes             []Vote         `json:"-"`
+	Deleted           gorm.DeletedAt `json:"-"`
 }
 
+// ValidVoter is a voter in the voter roll. Locale is the language the voter
+// gets notifications in, and Weight how many votes the voter's vote counts as
 type ValidVoter struct {
-	Email string `gorm:"primaryKey"`
+	Email  string `gorm:"primaryKey"`
+	Locale string `gorm:"not null;default:''"`
+	Weight int    `gorm:"not null;default:1"`
+}
+
+// SentNotification records that a notification has been sent to a voter, so
+// that it is only sent once
+type SentNotification struct {
+	Email      string    `gorm:"primaryKey"`
+	ElectionID uuid.UUID `gorm:"primaryKey"`
+	Kind       string    `gorm:"primaryKey"`
+	SentAt     time.Time `gorm:"not null"`
 }
 
 type CastedVote struct {
-	Email      string     `gorm:"primaryKey"`
-	ElectionID uuid.UUID  `gorm:"primaryKey"`
-	User       ValidVoter `gorm:"foreignKey:Email;references:Email"`
-	Election   Election   `gorm:"foreignKey:ID;references:ElectionID"`
+	Email      string    `gorm:"primaryKey"`
+	ElectionID uuid.UUID `gorm:"primaryKey"`
+	// Proxy is the holder of the proxy that the vote was cast with, or empty
+	// if the voter cast it
+	Proxy    string     `gorm:"not null;default:''"`
+	User     ValidVoter `gorm:"foreignKey:Email;references:Email"`
+	Election Election   `gorm:"foreignKey:ID;references:ElectionID"`
 }
 
-// All elections should contain two forces candidates "Vakant" and "Blank"
+// Symbolic candidates are options on the ballot that are not people, such as
+// leaving the post vacant. What they mean is decided by their SymbolicKind
 type Candidate struct {
 	ID           uuid.UUID      `gorm:"primaryKey" json:"id"`
 	Name         string         `gorm:"not null" json:"name"`
 	Presentation string         `gorm:"not null" json:"presentation"`
 	ElectionID   uuid.UUID      `gorm:"not null" json:"-"`
 	Symbolic     bool           `gorm:"not null;default:false" json:"symbolic"`
+	SymbolicKind SymbolicKind   `gorm:"not null;default:''" json:"symbolicKind"`
+	Position     int            `gorm:"not null;default:0" json:"position"`
+	Withdrawn    bool           `gorm:"not null;default:false" json:"withdrawn"`
+	Image        string         `gorm:"not null;default:''" json:"image"`
+	Links        Links          `gorm:"type:text;not null;default:'[]'" json:"links"`
+	Translations Translations   `gorm:"type:text;not null;default:'{}'" json:"translations"`
 	Election     Election       `json:"-"`
 	Deleted      gorm.DeletedAt `json:"-"`
+
+	// PresentationHTML is the presentation, written in markdown, rendered as
+	// sanitised HTML. It is not stored but rendered when loading the candidate
+	PresentationHTML string `gorm:"-" json:"presentationHtml"`
+}
+
+func (c *Candidate) AfterFind(tx *gorm.DB) (err error) {
+	c.PresentationHTML = util.RenderMarkdown(c.Presentation)
+	return nil
+}
+
+func (c *Candidate) AfterSave(tx *gorm.DB) (err error) {
+	c.PresentationHTML = util.RenderMarkdown(c.Presentation)
+	return nil
+}
+
+// SymbolicKind is the kind of a symbolic candidate, which decides how it is
+// counted. Candidates that are not symbolic have no kind
+type SymbolicKind string
+
+const (
+	// SymbolicVacant is a vote for leaving the post vacant
+	SymbolicVacant SymbolicKind = "vacant"
+	// SymbolicReopen is a vote for re-opening the nominations and holding
+	// the election again later
+	SymbolicReopen SymbolicKind = "reopen"
+	// SymbolicBlank is a blank vote, abstaining from ranking the candidates
+	// after it
+	SymbolicBlank SymbolicKind = "blank"
+)
+
+// SymbolicKinds are all kinds of symbolic candidates, in the order they are
+// shown on the ballot
+var SymbolicKinds = []SymbolicKind{SymbolicVacant, SymbolicReopen, SymbolicBlank}
+
+var symbolicNames = map[SymbolicKind]map[string]string{
+	SymbolicVacant: {"sv": util.VacantCandidate, "en": "Vacant"},
+	SymbolicReopen: {"sv": util.ReopenCandidate, "en": "Re-open nominations"},
+	SymbolicBlank:  {"sv": util.BlankCandidate, "en": "Blank"},
+}
+
+// Name returns the name of the symbolic candidate in the locale, or in the
+// default locale if it has no name in the locale
+func (k SymbolicKind) Name(locale string) string {
+	if name, ok := symbolicNames[k][locale]; ok {
+		return name
+	}
+	return symbolicNames[k][util.DefaultLocale]
+}
+
+// Valid checks if the kind is one of SymbolicKinds
+func (k SymbolicKind) Valid() bool {
+	_, ok := symbolicNames[k]
+	return ok
+}
+
+// Position returns the position of the symbolic candidate on the ballot,
+// which is after all candidates that are not symbolic
+func (k SymbolicKind) Position() int {
+	for i, kind := range SymbolicKinds {
+		if kind == k {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Link is a link on the profile of a candidate, e.g. to their nomination
+type Link struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Links are stored as a JSON array in a single column
+type Links []Link
+
+func (l Links) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
 }
 
-// VoteHash is purposefully not primaryKey/unique since it is theoretically
-// possible for two hashes to be the same, albeit quite unlikely. If it was
-// the case, however, it would prevent someone from voting, which is not good
+func (l *Links) Scan(value any) error {
+	*l = Links{}
+	return scanJSON(value, l)
+}
+
+// Translation is the content of an election or candidate in a locale other
+// than the default. Elections translate the name and description, and
+// candidates the presentation. Empty fields fall back to the default locale
+type Translation struct {
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+	Presentation string `json:"presentation,omitempty"`
+}
+
+// Translations are stored by locale as a JSON object in a single column
+type Translations map[string]Translation
+
+func (t Translations) Value() (driver.Value, error) {
+	if t == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(t)
+	return string(data), err
+}
+
+func (t *Translations) Scan(value any) error {
+	*t = Translations{}
+	return scanJSON(value, t)
+}
+
+// scanJSON scans a column containing JSON text into the value
+func scanJSON(value any, dest any) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	case []byte:
+		return json.Unmarshal(v, dest)
+	default:
+		return fmt.Errorf("can't scan %T into %T", value, dest)
+	}
+}
+
+// VoteHash is the receipt commitment of a vote, published together with the
+// ballot when the election is finalized. Hash is purposefully not
+// primaryKey/unique since it is theoretically possible for two hashes to be
+// the same, albeit quite unlikely. If it was the case, however, it would
+// prevent someone from voting, which is not good
 type VoteHash struct {
 	Hash       string    `gorm:"not null"`
 	ElectionID uuid.UUID `gorm:"not null"`
+	VoteID     uuid.UUID `gorm:"uniqueIndex"`
 }
 
+// Vote is an anonymised vote. Weight is copied from the voter roll when the
+// vote is cast, so that it can be counted without knowing the voter. A unique
+// weight still links the vote to its voter for anyone with the database, so
+// weights are never published with the ballots
 type Vote struct {
 	ID         uuid.UUID `gorm:"primaryKey"`
 	VoteTime   time.Time `gorm:"not null"`
 	ElectionID uuid.UUID `gorm:"not null"`
 	Rankings   []Ranking `gorm:"foreignKey:VoteID;references:ID;constraint:OnDelete:CASCADE"`
 	UserHash   string    ``
+	Weight     int       `gorm:"not null;default:1"`
 }
 
 func (v *Vote) BeforeDelete(tx *gorm.DB) (err error) {
@@ -68,8 +257,39 @@ func (v *Vote) BeforeDelete(tx *gorm.DB) (err error) {
 	return nil
 }
 
+// EncryptedBallot is the ballot of a vote in an election with encrypted
+// ballots, sealed to the key of the election. The rankings of the vote are
+// only stored when the ballots are decrypted at finalization
+type EncryptedBallot struct {
+	VoteID     uuid.UUID `gorm:"primaryKey"`
+	ElectionID uuid.UUID `gorm:"not null;index"`
+	Ciphertext []byte    `gorm:"not null"`
+}
+
 type Ranking struct {
 	VoteID      uuid.UUID `gorm:"PrimaryKey;constraint:OnDelete:CASCADE"`
 	Rank        int       `gorm:"PrimaryKey"`
 	CandidateID uuid.UUID `gorm:"not null"`
 }
+
+// Proxy lets the holder vote on behalf of the grantor (fullmakt) between
+// ValidFrom and ValidUntil. Proxies with an election are only valid for that
+// election, and proxies without one for all elections in the window, such as
+// those of a general meeting
+type Proxy struct {
+	ID         uuid.UUID  `gorm:"primaryKey" json:"id"`
+	Grantor    string     `gorm:"not null;index" json:"grantor"`
+	Holder     string     `gorm:"not null;index" json:"holder"`
+	ElectionID *uuid.UUID `json:"election"`
+	ValidFrom  time.Time  `gorm:"not null" json:"validFrom"`
+	ValidUntil time.Time  `gorm:"not null" json:"validUntil"`
+}
+
+// Role grants a permission to a user in the local role store. Roles with an
+// election are only valid for that election
+type Role struct {
+	ID         uuid.UUID  `gorm:"primaryKey" json:"id"`
+	Email      string     `gorm:"not null;index" json:"email"`
+	Permission string     `gorm:"not null" json:"permission"`
+	ElectionID *uuid.UUID `json:"election"`
+}
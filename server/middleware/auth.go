@@ -1,35 +1,62 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"net/url"
 	"strings"
 
 	"durn/config"
+	"durn/server/service"
 	"durn/server/util"
 
 	"github.com/gin-gonic/gin"
 )
 
-type loginResponse struct {
-	Email     string `json:"emails" Usage:"email"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Ugkthid   string `json:"ugkthid"`
-	User      string `json:"user"`
+// User is the identity of an authenticated user, as reported by an
+// authentication provider
+type User struct {
+	// Email is used to identify the user as a voter
+	Email string
+	// ID is used to look up permissions of the user
+	ID string
 }
 
-func Authenticate() gin.HandlerFunc {
-	conf := config.GetConfig()
-	url := conf.LOGIN_URL
-	key := conf.LOGIN_KEY
+// AuthenticationProvider verifies login tokens sent by the client
+type AuthenticationProvider interface {
+	// Authenticate returns the user that the token belongs to, or an error
+	// if the token is not valid
+	Authenticate(token string) (User, error)
+	// Check verifies that the provider is reachable and correctly configured
+	Check() error
+}
 
-	if check, err := http.Get(url + "/hello"); err != nil || check.StatusCode != 200 {
-		fmt.Println(err)
-		os.Exit(5)
+var ErrInvalidToken = errors.New("invalid token")
+
+// NewAuthenticationProvider creates the authentication provider specified
+// in the config. Supported providers are "login" and "oidc".
+func NewAuthenticationProvider(conf *config.Config) (AuthenticationProvider, error) {
+	switch conf.AUTHENTICATION_PROVIDER {
+	case "login":
+		return NewLoginProvider(conf.LOGIN_URL, conf.LOGIN_KEY), nil
+	case "oidc":
+		return NewOIDCProvider(OIDCOptions{
+			Issuer:     conf.OIDC_ISSUER,
+			Audience:   conf.OIDC_AUDIENCE,
+			JWKSURL:    conf.OIDC_JWKS_URL,
+			EmailClaim: conf.OIDC_EMAIL_CLAIM,
+			UserClaim:  conf.OIDC_USER_CLAIM,
+		})
+	default:
+		return nil, fmt.Errorf("unknown authentication provider '%s'", conf.AUTHENTICATION_PROVIDER)
 	}
+}
 
+// Authenticate is a middleware that verifies the bearer token in the
+// Authorization header using the given provider, and sets "user" and
+// "userid" in the context.
+func Authenticate(provider AuthenticationProvider) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := strings.Split(c.GetHeader("Authorization"), " ")
 		if len(authHeader) < 2 {
@@ -38,82 +65,123 @@ func Authenticate() gin.HandlerFunc {
 			return
 		}
 		token := authHeader[1]
-		requestURL := fmt.Sprintf("%s/verify/%s?api_key=%s", url, token, key)
 
-		var response loginResponse
-		if err := util.GetValidatedJsonFromURL(requestURL, &response, ""); err != nil {
+		user, err := provider.Authenticate(token)
+		if errors.Is(err, ErrInvalidToken) {
 			// TODO: proper logging
 			c.String(http.StatusUnauthorized, "Not logged in") // Unauthorized = Unauthenticated in http
 			c.Abort()
 			return
+		} else if err != nil {
+			fmt.Println("AUTHENTICATION FAILED", err)
+			c.String(http.StatusServiceUnavailable, "Failed to verify login")
+			c.Abort()
+			return
 		}
 
-		c.Set("user", response.Email)
-		c.Set("userid", response.User)
+		c.Set("user", user.Email)
+		c.Set("userid", user.ID)
 
 		c.Next()
 	}
 }
 
-type hivePermission struct {
-	Id    string `json:"id"`
-	Scope string `json:"scope"`
+// TokenFromQuery uses the token query parameter as bearer token when there is
+// no Authorization header. Only meant for routes used by browser APIs that
+// can't set headers, such as EventSource, since urls end up in logs
+func TokenFromQuery(c *gin.Context) {
+	if token := c.Query("token"); token != "" && c.GetHeader("Authorization") == "" {
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+	}
+	c.Next()
 }
 
-func Authorize() gin.HandlerFunc {
-	conf := config.GetConfig()
-	url := conf.HIVE_URL
-	token := conf.HIVE_API_KEY
+// Logger logs requests like gin.Logger, but with the token query parameter
+// redacted so that tokens used with TokenFromQuery do not end up in the logs
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		param.Path = redactToken(param.Path)
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
+		}
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			methodColor, param.Method, resetColor,
+			param.Path,
+			param.ErrorMessage,
+		)
+	})
+}
 
-	// because hive doesn't have a test endpoint we cannot verify connection
-	if _, err := http.Get(url + "/"); err != nil {
-		fmt.Println(err)
-		os.Exit(5)
+// redactToken replaces the value of the token query parameter in the path
+func redactToken(path string) string {
+	u, err := url.Parse(path)
+	if err != nil {
+		return path
 	}
+	query := u.Query()
+	if !query.Has("token") {
+		return path
+	}
+	query.Set("token", "REDACTED")
+	u.RawQuery = query.Encode()
+	return u.String()
+}
 
-	return func(c *gin.Context) {
-		user := c.GetString("userid")
-		requestURL := fmt.Sprintf("%s/api/v1/user/%s/permissions", url, user)
-
-		var response []hivePermission
-
-		if err := util.GetJsonFromURL(requestURL, &response, token); err != nil {
-			// TODO: PROPER LOGGING
-			fmt.Println("AUTHORIZATION FAILED", err)
-			response = []hivePermission{}
-		}
+// Providers are the authentication and authorization providers used by the
+// Auth middleware
+type Providers struct {
+	Authentication AuthenticationProvider
+	Authorization  AuthorizationProvider
+}
 
-		// we only care about PermIds, since all our perms are unscoped
-		perms := make([]string, len(response))
-		for i, v := range response {
-			perms[i] = v.Id
-		}
+// NewProviders creates the providers specified in the config. Lookups in
+// upstream services are cached and protected by circuit breakers so that they
+// are not overloaded.
+// Providers that can not be reached are retried with backoff, but are only
+// reported as not ready since they may come back later. Only configuration
+// errors are returned
+func NewProviders(conf *config.Config, roles *service.Roles) (Providers, error) {
+	util.SetRequestTimeout(conf.UPSTREAM_TIMEOUT)
+	cacheOptions := CacheOptions{
+		TTL:              conf.AUTH_CACHE_TTL,
+		MaxSize:          conf.AUTH_CACHE_SIZE,
+		BreakerThreshold: conf.CIRCUIT_BREAKER_THRESHOLD,
+		BreakerCooldown:  conf.CIRCUIT_BREAKER_COOLDOWN,
+	}
 
-		c.Set("perms", perms)
-		c.Next()
+	authentication, err := NewAuthenticationProvider(conf)
+	if err != nil {
+		return Providers{}, err
+	}
+	authorization, err := NewAuthorizationProvider(conf, roles, cacheOptions)
+	if err != nil {
+		return Providers{}, err
 	}
-}
 
-// Checks if the logged in user has the provided permission in Hive;
-// assumes Authentication and Authorization has been done
-func HasPerm(perm string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		perms, ok := c.Keys["perms"].([]string)
-		if !ok {
-			fmt.Print("error")
-			return
-		}
-		for _, val := range perms {
-			if val == perm {
-				c.Next()
-				return
-			}
-		}
-		c.String(http.StatusForbidden, "Insufficient permissions")
-		c.Abort()
+	if err := util.Retry(conf.STARTUP_RETRIES, conf.STARTUP_RETRY_DELAY, authentication.Check); err != nil {
+		fmt.Println("WARNING: authentication provider is not ready:", err)
 	}
+	if err := util.Retry(conf.STARTUP_RETRIES, conf.STARTUP_RETRY_DELAY, authorization.Check); err != nil {
+		fmt.Println("WARNING: authorization provider is not ready:", err)
+	}
+
+	return Providers{
+		Authentication: NewCachedAuthentication(authentication, cacheOptions),
+		Authorization:  authorization,
+	}, nil
 }
 
-func Auth() gin.HandlersChain {
-	return gin.HandlersChain{Authenticate(), Authorize()}
+// Auth returns the authentication and authorization middleware chain
+func Auth(providers Providers) gin.HandlersChain {
+	return gin.HandlersChain{
+		Authenticate(providers.Authentication),
+		Authorize(providers.Authorization),
+	}
 }
@@ -0,0 +1,17 @@
+package middleware
+
+import "testing"
+
+func TestRedactToken(t *testing.T) {
+	tests := map[string]string{
+		"/api/events":                      "/api/events",
+		"/api/events?token=secret":         "/api/events?token=REDACTED",
+		"/api/events/admin?a=1&token=abc.": "/api/events/admin?a=1&token=REDACTED",
+		"/api/election/1?locale=sv":        "/api/election/1?locale=sv",
+	}
+	for path, want := range tests {
+		if got := redactToken(path); got != want {
+			t.Errorf("redactToken(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
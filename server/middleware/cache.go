@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expvar"
+	"time"
+
+	"durn/server/util"
+)
+
+// cacheMetrics counts cache hits and misses and rejected upstream calls,
+// published at /api/metrics
+var cacheMetrics = expvar.NewMap("auth_cache")
+
+// CacheOptions configures the caching and circuit breaking of upstream lookups
+type CacheOptions struct {
+	TTL     time.Duration
+	MaxSize int
+	// BreakerThreshold is the amount of consecutive failures before calls
+	// to the upstream are stopped. Zero disables the circuit breaker
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// ExpiringAuthenticationProvider is an AuthenticationProvider whose tokens
+// expire, such as OIDC tokens
+type ExpiringAuthenticationProvider interface {
+	AuthenticationProvider
+	// AuthenticateUntil authenticates like Authenticate, and also returns
+	// when the token expires
+	AuthenticateUntil(token string) (User, time.Time, error)
+}
+
+// CachedAuthentication caches users of verified tokens, so that the upstream
+// provider is only asked once per token and time to live. Invalid tokens
+// are not cached, and tokens of an ExpiringAuthenticationProvider are not
+// cached past their expiry.
+type CachedAuthentication struct {
+	provider AuthenticationProvider
+	cache    *util.Cache[string, User]
+	breaker  *util.CircuitBreaker
+}
+
+func NewCachedAuthentication(provider AuthenticationProvider, options CacheOptions) *CachedAuthentication {
+	return &CachedAuthentication{
+		provider: provider,
+		cache:    util.NewCache[string, User](options.TTL, options.MaxSize),
+		breaker:  util.NewCircuitBreaker(options.BreakerThreshold, options.BreakerCooldown),
+	}
+}
+
+func (a *CachedAuthentication) Authenticate(token string) (User, error) {
+	// tokens are hashed so that they are not kept in memory in plain text
+	digest := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(digest[:])
+
+	if user, ok := a.cache.Get(key); ok {
+		cacheMetrics.Add("authentication_hits", 1)
+		return user, nil
+	}
+	cacheMetrics.Add("authentication_misses", 1)
+
+	var user User
+	var expires time.Time
+	err := a.breaker.Call(func() error {
+		var err error
+		if provider, ok := a.provider.(ExpiringAuthenticationProvider); ok {
+			user, expires, err = provider.AuthenticateUntil(token)
+		} else {
+			user, err = a.provider.Authenticate(token)
+		}
+		return err
+	}, func(err error) bool {
+		return !errors.Is(err, ErrInvalidToken)
+	})
+	if err != nil {
+		if errors.Is(err, util.ErrCircuitOpen) {
+			cacheMetrics.Add("authentication_rejected", 1)
+		}
+		return User{}, err
+	}
+
+	if expires.IsZero() {
+		a.cache.Set(key, user)
+	} else {
+		a.cache.SetUntil(key, user, expires)
+	}
+	return user, nil
+}
+
+func (a *CachedAuthentication) Check() error {
+	return a.provider.Check()
+}
+
+// CachedAuthorization caches the permissions of users, so that the upstream
+// provider is only asked once per user and time to live
+type CachedAuthorization struct {
+	provider AuthorizationProvider
+	cache    *util.Cache[User, []Permission]
+	breaker  *util.CircuitBreaker
+}
+
+func NewCachedAuthorization(provider AuthorizationProvider, options CacheOptions) *CachedAuthorization {
+	return &CachedAuthorization{
+		provider: provider,
+		cache:    util.NewCache[User, []Permission](options.TTL, options.MaxSize),
+		breaker:  util.NewCircuitBreaker(options.BreakerThreshold, options.BreakerCooldown),
+	}
+}
+
+func (a *CachedAuthorization) Permissions(user User) ([]Permission, error) {
+	if perms, ok := a.cache.Get(user); ok {
+		cacheMetrics.Add("permission_hits", 1)
+		return perms, nil
+	}
+	cacheMetrics.Add("permission_misses", 1)
+
+	var perms []Permission
+	err := a.breaker.Call(func() error {
+		var err error
+		perms, err = a.provider.Permissions(user)
+		return err
+	}, func(error) bool { return true })
+	if err != nil {
+		if errors.Is(err, util.ErrCircuitOpen) {
+			cacheMetrics.Add("permission_rejected", 1)
+		}
+		return nil, err
+	}
+
+	a.cache.Set(user, perms)
+	return perms, nil
+}
+
+func (a *CachedAuthorization) Check() error {
+	return a.provider.Check()
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"durn/config"
+	"durn/server/service"
+	"durn/server/service/servicetest"
+)
+
+var testCacheOptions = CacheOptions{TTL: time.Minute, MaxSize: 10}
+
+// countingProvider grants admin-read to everyone and counts the lookups
+type countingProvider struct {
+	lookups int
+}
+
+func (p *countingProvider) Permissions(user User) ([]Permission, error) {
+	p.lookups++
+	return []Permission{{ID: AdminReadPermission}}, nil
+}
+
+func (p *countingProvider) Check() error { return nil }
+
+func (p *countingProvider) Authenticate(token string) (User, error) {
+	p.lookups++
+	if token != "valid" {
+		return User{}, ErrInvalidToken
+	}
+	return User{Email: "user@kth.se", ID: "user"}, nil
+}
+
+func TestCachedAuthorization(t *testing.T) {
+	provider := &countingProvider{}
+	cached := NewCachedAuthorization(provider, testCacheOptions)
+	for i := 0; i < 2; i++ {
+		if _, err := cached.Permissions(User{Email: "user@kth.se", ID: "user"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.lookups != 1 {
+		t.Errorf("provider asked %d times, want 1", provider.lookups)
+	}
+}
+
+func TestCachedAuthentication(t *testing.T) {
+	provider := &countingProvider{}
+	cached := NewCachedAuthentication(provider, testCacheOptions)
+	for i := 0; i < 2; i++ {
+		if _, err := cached.Authenticate("valid"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cached.Authenticate("invalid"); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Authenticate() = %v, want ErrInvalidToken", err)
+		}
+	}
+	// Invalid tokens are not cached
+	if provider.lookups != 3 {
+		t.Errorf("provider asked %d times, want 3", provider.lookups)
+	}
+}
+
+// expiringProvider authenticates every token, which expires at expires
+type expiringProvider struct {
+	countingProvider
+	expires time.Time
+}
+
+func (p *expiringProvider) AuthenticateUntil(token string) (User, time.Time, error) {
+	user, err := p.Authenticate(token)
+	return user, p.expires, err
+}
+
+func TestCachedAuthenticationExpiry(t *testing.T) {
+	// Tokens that expire before the time to live are cached until then
+	provider := &expiringProvider{expires: time.Now().Add(time.Hour)}
+	cached := NewCachedAuthentication(provider, testCacheOptions)
+	for i := 0; i < 2; i++ {
+		if _, err := cached.Authenticate("valid"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.lookups != 1 {
+		t.Errorf("provider asked %d times, want 1", provider.lookups)
+	}
+
+	// Tokens that have expired, but are still accepted with the leeway of
+	// the provider, are not cached
+	provider = &expiringProvider{expires: time.Now().Add(-time.Second)}
+	cached = NewCachedAuthentication(provider, testCacheOptions)
+	for i := 0; i < 2; i++ {
+		if _, err := cached.Authenticate("valid"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.lookups != 2 {
+		t.Errorf("provider asked %d times, want 2", provider.lookups)
+	}
+}
+
+func TestLocalRolesNotCached(t *testing.T) {
+	roles := service.NewRoles(servicetest.NewRepository(t))
+	provider, err := NewAuthorizationProvider(&config.Config{AUTHORIZATION_PROVIDER: "local"}, roles, testCacheOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := User{Email: "user@kth.se", ID: "user"}
+
+	role, err := roles.Add(service.RoleParams{Email: user.Email, Permission: AdminWritePermission})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perms, err := provider.Permissions(user); err != nil || len(perms) != 1 {
+		t.Fatalf("Permissions() = %v, %v, want admin-write", perms, err)
+	}
+
+	// A removed role stops working at once
+	if err := roles.Remove(role.ID); err != nil {
+		t.Fatal(err)
+	}
+	if perms, err := provider.Permissions(user); err != nil || len(perms) != 0 {
+		t.Errorf("Permissions() after removing the role = %v, %v, want none", perms, err)
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"fmt"
+
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type hivePermission struct {
+	Id    string `json:"id"`
+	Scope string `json:"scope"`
+}
+
+// HiveProvider looks up permissions in Hive, the permission system of the
+// chapter. Hive permissions whose scope is an election id are scoped to that
+// election. Other scopes are not about elections, so those permissions are
+// treated as unscoped like they always have been
+type HiveProvider struct {
+	url   string
+	token string
+}
+
+func NewHiveProvider(url string, token string) *HiveProvider {
+	return &HiveProvider{url: url, token: token}
+}
+
+func (p *HiveProvider) Permissions(user User) ([]Permission, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/user/%s/permissions", p.url, user.ID)
+
+	var response []hivePermission
+	if err := util.GetJsonFromURL(requestURL, &response, p.token); err != nil {
+		return nil, err
+	}
+
+	perms := make([]Permission, len(response))
+	for i, v := range response {
+		perms[i] = Permission{ID: v.Id}
+		if _, err := uuid.FromString(v.Scope); err == nil {
+			perms[i].Scope = v.Scope
+		}
+	}
+	return perms, nil
+}
+
+// because hive doesn't have a test endpoint we can only verify that it
+// responds at all
+func (p *HiveProvider) Check() error {
+	_, err := util.GetStatus(p.url + "/")
+	return err
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHivePermissions(t *testing.T) {
+	const election = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/user/user/permissions" || r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]hivePermission{
+			{Id: AdminReadPermission},
+			{Id: AdminWritePermission, Scope: election},
+			// Scopes that are not elections don't restrict the permission
+			{Id: ObserverPermission, Scope: "sektionsmote"},
+		})
+	}))
+	defer server.Close()
+
+	perms, err := NewHiveProvider(server.URL, "key").Permissions(User{ID: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Permission{
+		{ID: AdminReadPermission},
+		{ID: AdminWritePermission, Scope: election},
+		{ID: ObserverPermission},
+	}
+	if !reflect.DeepEqual(perms, want) {
+		t.Errorf("Permissions() = %+v, want %+v", perms, want)
+	}
+}
+
+func TestElectionPermissions(t *testing.T) {
+	const election = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	const other = "6ba7b811-9dad-11d1-80b4-00c04fd430c8"
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		permissions []Permission
+		perm        string
+		election    string
+		status      int
+	}{
+		{"unscoped", []Permission{{ID: AdminWritePermission}}, AdminWritePermission, election, http.StatusOK},
+		{"unscoped on unscoped route", []Permission{{ID: AdminWritePermission}}, AdminWritePermission, "", http.StatusOK},
+		{"scoped", []Permission{{ID: AdminWritePermission, Scope: election}}, AdminWritePermission, election, http.StatusOK},
+		{"scoped to other election", []Permission{{ID: AdminWritePermission, Scope: other}}, AdminWritePermission, election, http.StatusForbidden},
+		{"scoped on unscoped route", []Permission{{ID: AdminWritePermission, Scope: election}}, AdminWritePermission, "", http.StatusForbidden},
+		{"observer reads", []Permission{{ID: ObserverPermission, Scope: election}}, AdminReadPermission, election, http.StatusOK},
+		{"unscoped observer", []Permission{{ID: ObserverPermission}}, AdminReadPermission, election, http.StatusForbidden},
+		{"other permission", []Permission{{ID: AdminReadPermission}}, AdminWritePermission, election, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("permissions", test.permissions)
+			})
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			r.GET("/election/:id", HasElectionPerm(test.perm), ok)
+			r.GET("/elections", HasPerm(test.perm), ok)
+
+			path := "/elections"
+			if test.election != "" {
+				path = "/election/" + test.election
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if w.Code != test.status {
+				t.Errorf("%s responded %d, want %d", path, w.Code, test.status)
+			}
+		})
+	}
+}
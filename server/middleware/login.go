@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"durn/server/util"
+)
+
+type loginResponse struct {
+	Email     string `json:"emails" Usage:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Ugkthid   string `json:"ugkthid"`
+	User      string `json:"user"`
+}
+
+// LoginProvider authenticates users against the chapter login service,
+// using its /verify/<token>?api_key=<key> endpoint
+type LoginProvider struct {
+	url string
+	key string
+}
+
+func NewLoginProvider(url string, key string) *LoginProvider {
+	return &LoginProvider{url: url, key: key}
+}
+
+// Authenticate verifies the token with the login service. Only responses
+// that are not a valid user are treated as invalid tokens, failing to reach
+// the service is returned as other errors
+func (p *LoginProvider) Authenticate(token string) (User, error) {
+	requestURL := fmt.Sprintf("%s/verify/%s?api_key=%s", p.url, token, p.key)
+
+	data, err := util.GetFromUrl(requestURL, "")
+	if err != nil {
+		return User{}, err
+	}
+	var response loginResponse
+	if err := util.ValidateJson(data, &response); err != nil {
+		return User{}, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	return User{Email: response.Email, ID: response.User}, nil
+}
+
+func (p *LoginProvider) Check() error {
+	status, err := util.GetStatus(p.url + "/hello")
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("login service responded with status %d", status)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"durn/server/util"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksRefreshInterval is the shortest time between two fetches of the key
+// set, so that tokens with unknown key ids can't be used to flood the provider
+const jwksRefreshInterval = time.Minute
+
+type OIDCOptions struct {
+	// Issuer is the expected "iss" claim, and is used to discover the key
+	// set if JWKSURL is empty
+	Issuer string
+	// Audience is the expected "aud" claim, usually the client id, so that
+	// tokens issued to other clients are rejected
+	Audience string
+	// JWKSURL is the location of the key set used to validate tokens
+	JWKSURL string
+	// EmailClaim is the claim containing the email address of the user
+	EmailClaim string
+	// UserClaim is the claim containing the id of the user
+	UserClaim string
+}
+
+// errKeysUnavailable is returned when the key set can't be fetched, which
+// says nothing about whether the token is valid
+var errKeysUnavailable = errors.New("key set unavailable")
+
+// OIDCProvider authenticates users by validating OpenID Connect ID tokens
+// (JWTs) against the key set published by the identity provider. Tokens
+// where the provider says that the email is not verified are rejected
+type OIDCProvider struct {
+	options OIDCOptions
+	parser  *jwt.Parser
+
+	// m protects the fields below, and is not held while fetching keys
+	m           sync.Mutex
+	jwksURL     string
+	keys        map[string]any
+	lastRefresh time.Time
+}
+
+func NewOIDCProvider(options OIDCOptions) (*OIDCProvider, error) {
+	if options.Issuer == "" {
+		return nil, errors.New("OIDC_ISSUER must be set to use the oidc provider")
+	}
+	if options.Audience == "" {
+		return nil, errors.New("OIDC_AUDIENCE must be set to use the oidc provider")
+	}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(options.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+		jwt.WithAudience(options.Audience),
+	}
+
+	return &OIDCProvider{
+		options: options,
+		parser:  jwt.NewParser(parserOptions...),
+		jwksURL: options.JWKSURL,
+		keys:    map[string]any{},
+	}, nil
+}
+
+func (p *OIDCProvider) Authenticate(token string) (User, error) {
+	user, _, err := p.AuthenticateUntil(token)
+	return user, err
+}
+
+// AuthenticateUntil authenticates like Authenticate, and also returns when
+// the token expires
+func (p *OIDCProvider) AuthenticateUntil(token string) (User, time.Time, error) {
+	claims := jwt.MapClaims{}
+	var unavailable error
+	_, err := p.parser.ParseWithClaims(token, claims, func(token *jwt.Token) (any, error) {
+		key, err := p.keyFunc(token)
+		if errors.Is(err, errKeysUnavailable) {
+			unavailable = err
+		}
+		return key, err
+	})
+	if unavailable != nil {
+		return User{}, time.Time{}, unavailable
+	} else if err != nil {
+		return User{}, time.Time{}, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	if verified, ok := claims["email_verified"]; ok && verified != true && verified != "true" {
+		return User{}, time.Time{}, fmt.Errorf("%w: email is not verified", ErrInvalidToken)
+	}
+
+	email, _ := claims[p.options.EmailClaim].(string)
+	id, _ := claims[p.options.UserClaim].(string)
+	if email == "" || id == "" {
+		return User{}, time.Time{}, fmt.Errorf(
+			"%w: missing claim '%s' or '%s'", ErrInvalidToken, p.options.EmailClaim, p.options.UserClaim,
+		)
+	}
+
+	// The expiry is required by the parser
+	expires, err := claims.GetExpirationTime()
+	if err != nil || expires == nil {
+		return User{}, time.Time{}, fmt.Errorf("%w: missing expiry", ErrInvalidToken)
+	}
+	return User{Email: email, ID: id}, expires.Time, nil
+}
+
+func (p *OIDCProvider) Check() error {
+	return p.refreshKeys()
+}
+
+// keyFunc finds the public key matching the key id of a token, refetching
+// the key set if the key id is unknown since the provider may have rotated keys
+func (p *OIDCProvider) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.m.Lock()
+	key, ok := p.keys[kid]
+	previousRefresh := p.lastRefresh
+	refresh := !ok && time.Since(previousRefresh) >= jwksRefreshInterval
+	if refresh {
+		// Other requests with unknown key ids wait for the next interval
+		// instead of fetching the key set at the same time
+		p.lastRefresh = time.Now()
+	}
+	p.m.Unlock()
+
+	if ok {
+		return key, nil
+	}
+	if !refresh {
+		return nil, fmt.Errorf("unknown key id '%s'", kid)
+	}
+	if err := p.refreshKeys(); err != nil {
+		p.m.Lock()
+		p.lastRefresh = previousRefresh
+		p.m.Unlock()
+		return nil, err
+	}
+
+	p.m.Lock()
+	key, ok = p.keys[kid]
+	p.m.Unlock()
+	if ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id '%s'", kid)
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// refreshKeys fetches the key set of the provider, discovering its location
+// first if it is not known. Takes the lock only to read and store the result,
+// so that other requests are not blocked by the fetch. Failures wrap
+// errKeysUnavailable
+func (p *OIDCProvider) refreshKeys() error {
+	p.m.Lock()
+	url := p.jwksURL
+	p.m.Unlock()
+	if url == "" {
+		var err error
+		if url, err = discoverJWKSURL(p.options.Issuer); err != nil {
+			return fmt.Errorf("%w: %s", errKeysUnavailable, err)
+		}
+		p.m.Lock()
+		p.jwksURL = url
+		p.m.Unlock()
+	}
+
+	var response struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := util.GetJsonFromURL(url, &response, ""); err != nil {
+		return fmt.Errorf("%w: %s", errKeysUnavailable, err)
+	}
+
+	keys := map[string]any{}
+	for _, jwk := range response.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			fmt.Printf("Skipping key '%s' in key set: %s\n", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("%w: no usable keys found at %s", errKeysUnavailable, url)
+	}
+	p.m.Lock()
+	p.keys = keys
+	p.lastRefresh = time.Now()
+	p.m.Unlock()
+	return nil
+}
+
+func (jwk jsonWebKey) publicKey() (any, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", jwk.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// discoverJWKSURL finds the key set of an issuer through its OpenID
+// Connect discovery document
+func discoverJWKSURL(issuer string) (string, error) {
+	var response struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	url := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := util.GetJsonFromURL(url, &response, ""); err != nil {
+		return "", err
+	}
+	if response.JWKSURI == "" {
+		return "", fmt.Errorf("no jwks_uri in discovery document at %s", url)
+	}
+	return response.JWKSURI, nil
+}
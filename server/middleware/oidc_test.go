@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://login.example.com"
+	testAudience = "durn"
+)
+
+// testKeySet serves a JSON web key set with the public parts of its keys
+type testKeySet struct {
+	m       sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	fetches int
+	fail    bool
+}
+
+func (s *testKeySet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.fetches++
+	if s.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var keys []jsonWebKey
+	for kid, key := range s.keys {
+		keys = append(keys, jsonWebKey{
+			Kid: kid,
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+}
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// newTestOIDC returns a provider using a key set with the key "a"
+func newTestOIDC(t *testing.T) (*OIDCProvider, *testKeySet, *rsa.PrivateKey) {
+	t.Helper()
+	key := newTestKey(t)
+	keySet := &testKeySet{keys: map[string]*rsa.PrivateKey{"a": key}}
+	server := httptest.NewServer(keySet)
+	t.Cleanup(server.Close)
+
+	provider, err := NewOIDCProvider(OIDCOptions{
+		Issuer:     testIssuer,
+		Audience:   testAudience,
+		JWKSURL:    server.URL,
+		EmailClaim: "email",
+		UserClaim:  "sub",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider, keySet, key
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"sub":   "user",
+		"email": "user@kth.se",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestOIDCRequiresAudience(t *testing.T) {
+	if _, err := NewOIDCProvider(OIDCOptions{Issuer: testIssuer}); err == nil {
+		t.Error("NewOIDCProvider() without an audience succeeded")
+	}
+}
+
+func TestOIDCAuthenticate(t *testing.T) {
+	provider, _, key := newTestOIDC(t)
+
+	user, err := provider.Authenticate(signToken(t, key, "a", validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "user@kth.se" || user.ID != "user" {
+		t.Errorf("Authenticate() = %+v, want user@kth.se", user)
+	}
+	claims := validClaims()
+	if _, expires, err := provider.AuthenticateUntil(signToken(t, key, "a", claims)); err != nil || expires.Unix() != claims["exp"] {
+		t.Errorf("AuthenticateUntil() = %v, %v, want the expiry %v", expires, err, claims["exp"])
+	}
+
+	with := func(name string, value any) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"other key", signToken(t, newTestKey(t), "a", validClaims())},
+		{"wrong issuer", signToken(t, key, "a", with("iss", "https://evil.example.com"))},
+		{"wrong audience", signToken(t, key, "a", with("aud", "other-client"))},
+		{"expired", signToken(t, key, "a", with("exp", time.Now().Add(-time.Hour).Unix()))},
+		{"no expiry", signToken(t, key, "a", with("exp", nil))},
+		{"unverified email", signToken(t, key, "a", with("email_verified", false))},
+		{"missing email", signToken(t, key, "a", with("email", nil))},
+		{"malformed", "not.a.token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := provider.Authenticate(test.token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Authenticate() = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	if _, err := provider.Authenticate(signToken(t, key, "a", with("email_verified", true))); err != nil {
+		t.Errorf("Authenticate() with a verified email = %v", err)
+	}
+}
+
+func TestOIDCRefetchesUnknownKey(t *testing.T) {
+	provider, keySet, key := newTestOIDC(t)
+	if _, err := provider.Authenticate(signToken(t, key, "a", validClaims())); err != nil {
+		t.Fatal(err)
+	}
+
+	// The provider rotates its keys. Unknown key ids are refetched at most
+	// once per interval
+	rotated := newTestKey(t)
+	keySet.m.Lock()
+	keySet.keys["b"] = rotated
+	keySet.m.Unlock()
+	token := signToken(t, rotated, "b", validClaims())
+	if _, err := provider.Authenticate(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate() right after a fetch = %v, want ErrInvalidToken", err)
+	}
+	if keySet.fetches != 1 {
+		t.Errorf("key set fetched %d times, want 1", keySet.fetches)
+	}
+
+	provider.lastRefresh = time.Now().Add(-jwksRefreshInterval)
+	if _, err := provider.Authenticate(token); err != nil {
+		t.Errorf("Authenticate() with a rotated key = %v", err)
+	}
+	if keySet.fetches != 2 {
+		t.Errorf("key set fetched %d times, want 2", keySet.fetches)
+	}
+}
+
+func TestOIDCKeySetUnavailable(t *testing.T) {
+	provider, keySet, key := newTestOIDC(t)
+	keySet.fail = true
+
+	// A failure to fetch the keys is not the fault of the token, and is
+	// responded to with 503 instead of 401
+	_, err := provider.Authenticate(signToken(t, key, "a", validClaims()))
+	if err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate() without keys = %v, want an error that is not ErrInvalidToken", err)
+	}
+
+	// The next request tries again
+	keySet.fail = false
+	if _, err := provider.Authenticate(signToken(t, key, "a", validClaims())); err != nil {
+		t.Errorf("Authenticate() after the key set is back = %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"durn/config"
+	"durn/server/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	AdminReadPermission  = service.AdminReadPermission
+	AdminWritePermission = service.AdminWritePermission
+	ObserverPermission   = service.ObserverPermission
+)
+
+// Permission is a permission held by a user. Permissions with an empty scope
+// are valid for all elections, otherwise the scope is the id of the only
+// election the permission is valid for
+type Permission struct {
+	ID    string `json:"id"`
+	Scope string `json:"scope,omitempty"`
+}
+
+// AuthorizationProvider looks up the permissions of authenticated users
+type AuthorizationProvider interface {
+	// Permissions returns all permissions held by the user
+	Permissions(user User) ([]Permission, error)
+	// Check verifies that the provider is reachable and correctly configured
+	Check() error
+}
+
+// NewAuthorizationProvider creates the authorization provider specified in the
+// config. Supported providers are "hive" and "local", and several providers
+// can be combined by separating them with commas, e.g. "hive,local".
+// Hive lookups are cached with the options. The local role store is read
+// through the given roles service and is not cached, so that removed roles
+// stop working at once
+func NewAuthorizationProvider(conf *config.Config, roles *service.Roles, cacheOptions CacheOptions) (AuthorizationProvider, error) {
+	var providers multiProvider
+	for _, name := range strings.Split(conf.AUTHORIZATION_PROVIDER, ",") {
+		switch strings.TrimSpace(name) {
+		case "hive":
+			providers = append(providers, NewCachedAuthorization(NewHiveProvider(conf.HIVE_URL, conf.HIVE_API_KEY), cacheOptions))
+		case "local":
+			providers = append(providers, NewLocalRoleProvider(roles, splitList(conf.LOCAL_ADMINS)))
+		default:
+			return nil, fmt.Errorf("unknown authorization provider '%s'", name)
+		}
+	}
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return providers, nil
+}
+
+// multiProvider grants the union of the permissions granted by all its
+// providers. Fails if any of the providers fail
+type multiProvider []AuthorizationProvider
+
+func (m multiProvider) Permissions(user User) ([]Permission, error) {
+	perms := []Permission{}
+	for _, provider := range m {
+		p, err := provider.Permissions(user)
+		if err != nil {
+			return perms, err
+		}
+		perms = append(perms, p...)
+	}
+	return perms, nil
+}
+
+func (m multiProvider) Check() error {
+	var errs []string
+	for _, provider := range m {
+		if err := provider.Check(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Authorize is a middleware that fetches the permissions of the logged in
+// user and sets "perms" (the ids of all unscoped permissions) and
+// "permissions" (all permissions) in the context.
+// If the lookup fails the user is treated as having no permissions, and
+// the error is set as "permsError" so that routes requiring permissions can
+// report it. Assumes Authentication has been done
+func Authorize(provider AuthorizationProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := User{Email: c.GetString("user"), ID: c.GetString("userid")}
+
+		permissions, err := provider.Permissions(user)
+		if err != nil {
+			// TODO: PROPER LOGGING
+			fmt.Println("AUTHORIZATION FAILED", err)
+			c.Set("permsError", err)
+			permissions = []Permission{}
+		}
+
+		perms := []string{}
+		for _, p := range permissions {
+			if p.Scope == "" {
+				perms = append(perms, p.ID)
+			}
+		}
+
+		c.Set("perms", perms)
+		c.Set("permissions", permissions)
+		c.Next()
+	}
+}
+
+// HasPerm checks if the logged in user has the provided unscoped permission;
+// assumes Authentication and Authorization has been done
+func HasPerm(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if Permitted(c, perm, "") {
+			c.Next()
+			return
+		}
+		denyPermission(c)
+	}
+}
+
+// HasElectionPerm checks if the logged in user has the provided permission
+// for the election given by the "id" route parameter, either unscoped or
+// scoped to that election. Observers are granted admin-read for their election.
+// Assumes Authentication and Authorization has been done
+func HasElectionPerm(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if Permitted(c, perm, c.Param("id")) {
+			c.Next()
+			return
+		}
+		denyPermission(c)
+	}
+}
+
+// HasAnyPerm checks if the logged in user has any of the provided permissions,
+// with any scope. Used for routes that filter their results by permission
+func HasAnyPerm(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, _ := c.Keys["permissions"].([]Permission)
+		for _, p := range permissions {
+			for _, perm := range perms {
+				if p.ID == perm {
+					c.Next()
+					return
+				}
+			}
+		}
+		denyPermission(c)
+	}
+}
+
+// Permitted checks if the logged in user has the permission for the specified
+// election. An empty election only matches unscoped permissions.
+func Permitted(c *gin.Context, perm string, election string) bool {
+	permissions, _ := c.Keys["permissions"].([]Permission)
+	for _, p := range permissions {
+		if p.Scope != "" && p.Scope != election {
+			continue
+		}
+		if p.ID == perm || (perm == AdminReadPermission && p.ID == ObserverPermission && p.Scope != "") {
+			return true
+		}
+	}
+	return false
+}
+
+func denyPermission(c *gin.Context) {
+	if _, failed := c.Get("permsError"); failed {
+		c.String(http.StatusServiceUnavailable, "Failed to fetch permissions")
+	} else {
+		c.String(http.StatusForbidden, "Insufficient permissions")
+	}
+	c.Abort()
+}
+
+func splitList(list string) []string {
+	var res []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	database "durn/server/db"
+	"durn/server/service"
+)
+
+// LocalRoleProvider looks up permissions in the roles table of the database,
+// which is managed through the API. Users listed as admins are always
+// granted unscoped admin-read and admin-write, so that there is someone
+// that can manage the roles
+type LocalRoleProvider struct {
+	roles  *service.Roles
+	admins map[string]bool
+}
+
+func NewLocalRoleProvider(roles *service.Roles, admins []string) *LocalRoleProvider {
+	p := &LocalRoleProvider{roles: roles, admins: map[string]bool{}}
+	for _, admin := range admins {
+		p.admins[admin] = true
+	}
+	return p
+}
+
+func (p *LocalRoleProvider) Permissions(user User) ([]Permission, error) {
+	perms := []Permission{}
+	if p.admins[user.Email] {
+		perms = append(perms,
+			Permission{ID: AdminReadPermission},
+			Permission{ID: AdminWritePermission},
+		)
+	}
+
+	roles, err := p.roles.Of(user.Email)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		perm := Permission{ID: role.Permission}
+		if role.ElectionID != nil {
+			perm.Scope = role.ElectionID.String()
+		}
+		perms = append(perms, perm)
+	}
+	return perms, nil
+}
+
+func (p *LocalRoleProvider) Check() error {
+	return database.Ping()
+}
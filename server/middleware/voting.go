@@ -1,28 +1,32 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
-	database "durn/server/db"
+	"durn/server/service"
+	"durn/server/util"
 )
 
 // AllowedToVote is a middleware that checks if the user is allowed to vote.
 // If they are not, the request is interrupted
 // Assumes Auth middleware has been run before
-func AllowedToVote() gin.HandlerFunc {
+func AllowedToVote(voters *service.Voters) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		user := c.GetString("user")
-
-		db := database.GetDB()
-		if db.Find(&database.ValidVoter{Email: user}).RowsAffected == 0 {
+		allowed, err := voters.IsAllowed(c.GetString("user"))
+		if err != nil {
+			fmt.Println(err)
+			c.String(http.StatusInternalServerError, util.RequestFailedMessage)
+			c.Abort()
+			return
+		}
+		if !allowed {
 			c.String(http.StatusForbidden, "Not registered as a valid voter")
 			c.Abort()
-			database.ReleaseDB()
 			return
 		}
-		database.ReleaseDB()
 		c.Next()
 	}
 }
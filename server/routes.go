@@ -1,46 +1,100 @@
 package server
 
 import (
-	"net/http"
+	"expvar"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	cors "github.com/rs/cors/wrapper/gin"
 
+	"durn/config"
 	"durn/server/actions"
 	"durn/server/middleware"
+	"durn/server/service"
 
 	"durn/server/db"
 )
 
-func InitRoutes(r *gin.RouterGroup) {
-	db.InitDB()
+func InitRoutes(r *gin.RouterGroup) error {
+	if err := db.InitDB(); err != nil {
+		return err
+	}
 
-	r.Use(cors.New(cors.Options{}))
+	repo := service.NewGormRepository(db.GetDB())
+	if err := actions.Init(repo, config.GetConfig()); err != nil {
+		return err
+	}
 
-	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	providers, err := middleware.NewProviders(config.GetConfig(), service.NewRoles(repo))
+	if err != nil {
+		return err
+	}
 
-	auth := r.Group("/", middleware.Auth()...)
+	addRoutes(r, repo, providers)
+	go actions.WatchVotingWindows(5 * time.Second)
+	go actions.RunNotifications(time.Minute)
 
-	auth.GET("/validate-token", actions.ValidateToken)
+	return nil
+}
+
+// addRoutes adds all routes of the API to the router group, using the
+// providers for logging in. The actions must have been initialized with the
+// same repository
+func addRoutes(r *gin.RouterGroup, repo service.Repository, providers middleware.Providers) {
+	r.Use(cors.New(cors.Options{}))
 
-	write := auth.Group("/", middleware.HasPerm("admin-write"))
-	read := auth.Group("/", middleware.HasPerm("admin-read"))
-	vote := auth.Group("/", middleware.AllowedToVote())
+	r.GET("/healthz", actions.Healthz)
+	r.GET("/readyz", actions.Readyz(map[string]func() error{
+		"database":       db.Ping,
+		"authentication": providers.Authentication.Check,
+		"authorization":  providers.Authorization.Check,
+	}))
+	// Assets are public since they are loaded by the browser without a token
+	r.GET("/assets/:name", actions.GetAsset)
+	// Receipts can be verified by anyone, also without being able to log in
+	r.GET("/receipts/key", actions.GetReceiptKey)
+	r.POST("/receipts/verify", actions.VerifyReceipt)
+
+	auth := r.Group("/", middleware.Auth(providers)...)
+	events := r.Group("/", append(gin.HandlersChain{middleware.TokenFromQuery}, middleware.Auth(providers)...)...)
 
-	read.GET("/elections", actions.GetElections)
-	read.GET("/election/:id", actions.GetElection)
+	auth.GET("/validate-token", actions.ValidateToken)
+
+	write := auth.Group("/", middleware.HasPerm(middleware.AdminWritePermission))
+	read := auth.Group("/", middleware.HasPerm(middleware.AdminReadPermission))
+	// Metrics include the command line and memory statistics of the process
+	read.GET("/metrics", gin.WrapH(expvar.Handler()))
+	vote := auth.Group("/", middleware.AllowedToVote(service.NewVoters(repo)))
+
+	// Routes for a specific election also accept permissions scoped to that election
+	electionWrite := auth.Group("/", middleware.HasElectionPerm(middleware.AdminWritePermission))
+	electionRead := auth.Group("/", middleware.HasElectionPerm(middleware.AdminReadPermission))
+	anyRead := auth.Group("/", middleware.HasAnyPerm(
+		middleware.AdminReadPermission, middleware.AdminWritePermission, middleware.ObserverPermission,
+	))
+
+	anyRead.GET("/elections", actions.GetElections)
+	electionRead.GET("/election/:id", actions.GetElection)
 	auth.GET("/elections/public", actions.GetPublicElections)
 	auth.GET("/election/public/:id", actions.GetPublicElection)
+	auth.GET("/election/public/:id/ballots", actions.GetBulletinBoard)
+	auth.GET("/election/public/:id/ballots/proof/:commitment", actions.GetBallotProof)
 
 	write.POST("/election/create", actions.CreateElection)
-	write.PATCH("/election/:id/edit", actions.EditElection)
+	write.POST("/elections/import", actions.ImportElections)
+	electionWrite.PATCH("/election/:id/edit", actions.EditElection)
 	// write.PUT("/election/:id/publish", actions.PublishElection)
 	// write.PUT("/election/:id/unpublish", actions.UnpublishElection)
-	write.PUT("/election/:id/finalize", actions.FinalizeElection)
-	write.POST("/election/:id/delete", actions.DeleteElection)
+	electionWrite.PUT("/election/:id/finalize", actions.FinalizeElection)
+	electionWrite.POST("/election/:id/encrypt", actions.EncryptElection)
+	electionWrite.POST("/election/:id/delete", actions.DeleteElection)
 
-	write.POST("/election/:id/candidate/add", actions.AddCandidate)
+	electionWrite.POST("/election/:id/candidate/add", actions.AddCandidate)
+	electionWrite.PUT("/election/:id/candidates/order", actions.OrderCandidates)
+	electionWrite.PUT("/election/:id/symbolic-candidates", actions.SetSymbolicCandidates)
 	write.PUT("/election/candidate/:id/edit", actions.EditCandidate)
+	write.POST("/election/candidate/:id/image", actions.UploadCandidateImage)
+	write.POST("/election/candidate/:id/image/delete", actions.RemoveCandidateImage)
 	write.POST("/election/candidate/:id/delete", actions.RemoveCandidate)
 
 	read.GET("/voters", actions.GetVoters)
@@ -48,13 +102,29 @@ func InitRoutes(r *gin.RouterGroup) {
 	write.DELETE("/voters/remove", actions.RemoveVoters)
 	vote.GET("/voter/allowed", actions.UserAllowedToVote)
 
+	read.GET("/proxies", actions.GetProxies)
+	write.POST("/proxy/add", actions.AddProxy)
+	write.POST("/proxy/:id/delete", actions.RemoveProxy)
+	vote.GET("/voter/proxies", actions.GetHeldProxies)
+
+	read.GET("/roles", actions.GetRoles)
+	write.POST("/role/add", actions.AddRole)
+	write.POST("/role/:id/delete", actions.RemoveRole)
+
 	vote.POST("/election/:id/vote", actions.CastVote)
 	auth.GET("/election/:id/has-voted", actions.HasVoted)
-	read.GET("/election/:id/votes", actions.GetVotes)
-	read.GET("/election/:id/count", actions.CountVotesSchultze)
-	write.GET("/election/:id/vote-count", actions.GetVoteCount)
+	electionRead.GET("/election/:id/votes", actions.GetVotes)
+	electionRead.GET("/election/:id/count", actions.CountVotesSchultze)
+	electionRead.GET("/election/:id/validity", actions.GetValidity)
+	electionRead.GET("/election/:id/export", actions.ExportBallots)
+	electionWrite.GET("/election/:id/vote-count", actions.GetVoteCount)
 	// read.GET("/election/:id/countOld", actions.CountVotes)
 	vote.GET("/election/hashes", actions.GetHashes)
 
 	write.DELETE("/elections/nuke", actions.NukeElections)
+
+	events.GET("/events", actions.GetEvents)
+	events.GET("/events/admin", middleware.HasAnyPerm(
+		middleware.AdminReadPermission, middleware.AdminWritePermission,
+	), actions.GetAdminEvents)
 }
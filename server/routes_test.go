@@ -0,0 +1,200 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"durn/config"
+	"durn/server/actions"
+	"durn/server/middleware"
+	"durn/server/service"
+	"durn/server/service/servicetest"
+	"durn/server/util"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// adminProviders log in everyone as an admin with all permissions
+type adminProviders struct{}
+
+func (adminProviders) Authenticate(token string) (middleware.User, error) {
+	return middleware.User{Email: "admin@kth.se", ID: "admin"}, nil
+}
+
+func (adminProviders) Permissions(user middleware.User) ([]middleware.Permission, error) {
+	return []middleware.Permission{
+		{ID: middleware.AdminReadPermission},
+		{ID: middleware.AdminWritePermission},
+	}, nil
+}
+
+func (adminProviders) Check() error {
+	return nil
+}
+
+// TestResultsHiddenUntilClose checks that no GET route shows ballots or
+// results before voting has closed, even to admins and if the election is
+// finalized early. Only the turnout, and the validity under the rules on the
+// turnout, can be seen. Every GET route has to be listed, so that new routes
+// are checked too
+func TestResultsHiddenUntilClose(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := servicetest.NewRepository(t)
+	if err := actions.Init(repo, &config.Config{
+		BLOB_DIR:       t.TempDir(),
+		MAX_IMAGE_SIZE: 1024,
+		RECEIPT_KEY:    "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	addRoutes(r.Group("/api"), repo, middleware.Providers{
+		Authentication: adminProviders{},
+		Authorization:  adminProviders{},
+	})
+
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	params := service.DefaultElectionParams()
+	params.Name = "Kassör"
+	params.OpenTime = util.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+	params.CloseTime = util.NullTime{Time: time.Now().Add(time.Hour), Valid: true}
+	params.MinNonBlankShare = 0.5
+	election, err := elections.Create(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Alice", "Bob"} {
+		if _, err := elections.AddCandidate(election.ID, service.CandidateParams{Name: name}, time.Now().Add(-2*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if election, err = elections.Get(election.ID); err != nil {
+		t.Fatal(err)
+	}
+	// The ballots rank the candidates in reverse order
+	var ranking []uuid.UUID
+	for _, candidate := range election.Candidates {
+		ranking = append([]uuid.UUID{candidate.ID}, ranking...)
+	}
+	if err := service.NewVoters(repo).Add([]string{"admin@kth.se"}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	var commitment string
+	for i := 0; i < 3; i++ {
+		receipt, err := votes.Cast(election.ID, fmt.Sprintf("%d@kth.se", i), "", ranking, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		commitment = receipt.Commitment
+	}
+	if _, err := elections.Finalize(election.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Routes that show ballots or results, and must not respond before
+	// voting has closed
+	hidden := map[string]bool{
+		"/api/election/:id/votes":                            true,
+		"/api/election/:id/count":                            true,
+		"/api/election/:id/export":                           true,
+		"/api/election/public/:id/ballots":                   true,
+		"/api/election/public/:id/ballots/proof/:commitment": true,
+	}
+	// Routes that respond, but must not include any ballots
+	visible := map[string]bool{
+		"/api/healthz":                 true,
+		"/api/readyz":                  true,
+		"/api/assets/:name":            true,
+		"/api/receipts/key":            true,
+		"/api/validate-token":          true,
+		"/api/metrics":                 true,
+		"/api/elections":               true,
+		"/api/election/:id":            true,
+		"/api/elections/public":        true,
+		"/api/election/public/:id":     true,
+		"/api/election/:id/has-voted":  true,
+		"/api/election/:id/vote-count": true,
+		"/api/election/:id/validity":   true,
+		"/api/election/hashes":         true,
+		"/api/voters":                  true,
+		"/api/voter/allowed":           true,
+		"/api/proxies":                 true,
+		"/api/voter/proxies":           true,
+		"/api/roles":                   true,
+	}
+	// Event streams don't end, and only send events published after they
+	// are opened, so they can't show earlier ballots
+	streams := map[string]bool{
+		"/api/events":       true,
+		"/api/events/admin": true,
+	}
+
+	pathOf := func(route string) string {
+		return strings.NewReplacer(
+			":id", election.ID.String(),
+			":commitment", commitment,
+			":name", "image.png",
+		).Replace(route)
+	}
+	get := func(route string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", pathOf(route), nil)
+		req.Header.Set("Authorization", "Bearer token")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The ranking of the ballots, as a JSON list of ids
+	var ids []string
+	for _, id := range ranking {
+		ids = append(ids, `"`+id.String()+`"`)
+	}
+	rankingJSON := strings.Join(ids, ",")
+
+	for _, route := range r.Routes() {
+		if route.Method != "GET" || streams[route.Path] {
+			continue
+		}
+		if !hidden[route.Path] && !visible[route.Path] {
+			t.Errorf("GET %s is not listed as hidden or visible before voting closes", route.Path)
+			continue
+		}
+		w := get(route.Path)
+		if hidden[route.Path] && w.Code == http.StatusOK {
+			t.Errorf("%s responded %d %q before voting closed", route.Path, w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), rankingJSON) {
+			t.Errorf("%s shows the ranking before voting closed: %q", route.Path, w.Body.String())
+		}
+		// The share of blank votes comes from the ballots too
+		if strings.Contains(w.Body.String(), "nonBlankShare\":") || strings.Contains(w.Body.String(), "not blank") {
+			t.Errorf("%s shows the share of blank votes before voting closed: %q", route.Path, w.Body.String())
+		}
+	}
+	if w := get("/api/election/:id/vote-count"); w.Code != http.StatusOK || w.Body.String() != "3" {
+		t.Errorf("vote-count responded %d %q, want 3", w.Code, w.Body.String())
+	}
+
+	stored, err := repo.GetElection(election.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.CloseTime.Time = time.Now().Add(-time.Second)
+	if err := repo.SaveElection(&stored); err != nil {
+		t.Fatal(err)
+	}
+	for route := range hidden {
+		if w := get(route); w.Code != http.StatusOK {
+			t.Errorf("%s responded %d %q after voting closed", route, w.Code, w.Body.String())
+		}
+	}
+	if w := get("/api/election/:id/votes"); !strings.Contains(w.Body.String(), rankingJSON) {
+		t.Errorf("votes do not show the ranking after voting closed: %q", w.Body.String())
+	}
+}
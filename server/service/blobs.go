@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// BlobStore stores uploaded files, such as images of candidates. Names are
+// generated by the services and never reused
+type BlobStore interface {
+	Save(name string, data []byte) error
+	// Open opens the blob for reading, returning ErrNotFound if there is none
+	Open(name string) (io.ReadSeekCloser, time.Time, error)
+	Delete(name string) error
+}
+
+// blobName matches the names generated for blobs, an uuid and an extension
+var blobName = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\.[a-z]+$`)
+
+// DirBlobStore stores blobs as files in a directory on local disk
+type DirBlobStore struct {
+	dir string
+}
+
+// NewDirBlobStore creates a blob store in the directory, creating it if it
+// does not exist
+func NewDirBlobStore(dir string) (*DirBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirBlobStore{dir: dir}, nil
+}
+
+// path returns the path of the blob, or false if the name is not a valid
+// blob name. This prevents names such as ../.env from escaping the directory
+func (s *DirBlobStore) path(name string) (string, bool) {
+	if !blobName.MatchString(name) {
+		return "", false
+	}
+	return filepath.Join(s.dir, name), true
+}
+
+func (s *DirBlobStore) Save(name string, data []byte) error {
+	path, ok := s.path(name)
+	if !ok {
+		return invalid("Invalid file name")
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (s *DirBlobStore) Open(name string) (io.ReadSeekCloser, time.Time, error) {
+	path, ok := s.path(name)
+	if !ok {
+		return nil, time.Time{}, ErrNotFound
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, time.Time{}, ErrNotFound
+	} else if err != nil {
+		return nil, time.Time{}, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, time.Time{}, err
+	}
+	return f, info.ModTime(), nil
+}
+
+func (s *DirBlobStore) Delete(name string) error {
+	path, ok := s.path(name)
+	if !ok {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	database "durn/server/db"
+
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	BulletinBoardFormat  = "durn-bulletin-board"
+	BulletinBoardVersion = 1
+)
+
+// BulletinBoard is the published list of all ballots of a finalized election.
+// Each ballot is listed with the commitment of its receipt, so that voters can
+// find their own ballot, and no timestamps or voters. The ballots are the
+// leaves of a Merkle tree, in order, whose root is stored when the election is
+// finalized, so anyone can count the ballots again and check that they are
+// the ballots that were published
+type BulletinBoard struct {
+	Format     string              `json:"format"`
+	Version    int                 `json:"version"`
+	Election   ExportedElection    `json:"election"`
+	Candidates []ExportedCandidate `json:"candidates"`
+	Root       string              `json:"root"`
+	Ballots    []BulletinEntry     `json:"ballots"`
+}
+
+// BulletinEntry is a ballot on the bulletin board. A ballot with a weight is
+// listed once for each unit of its weight, so that weights, which are often
+// unique to a delegation, can't link ballots to voters. The first entry has
+// the commitment of the receipt and the others commitments derived from it,
+// see unitCommitment
+type BulletinEntry struct {
+	Commitment string `json:"commitment"`
+	Ballot     Ballot `json:"ballot"`
+}
+
+// unitCommitment derives the commitment of the entry for the nth unit of the
+// weight of a ballot, the hex encoded sha3-256 of the commitment followed by
+// "#[n]". The first unit keeps the commitment of the receipt
+func unitCommitment(commitment string, n int) string {
+	if n == 0 {
+		return commitment
+	}
+	hash := sha3.Sum256([]byte(commitment + "#" + strconv.Itoa(n)))
+	return hex.EncodeToString(hash[:])
+}
+
+// MerkleProof proves that a ballot is included in the bulletin board with
+// the root. Path contains the siblings from the leaf up to the root
+type MerkleProof struct {
+	Root  string        `json:"root"`
+	Entry BulletinEntry `json:"entry"`
+	Path  []MerkleStep  `json:"path"`
+}
+
+// MerkleStep is a sibling on the path to the root. Left is true if the
+// sibling is to the left
+type MerkleStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// merkleLeaf hashes a ballot as a leaf of the tree, sha3-256 of a zero byte
+// followed by the commitment and ":[candidate-id]" for each ranked candidate
+func merkleLeaf(entry BulletinEntry) []byte {
+	h := sha3.New256()
+	h.Write([]byte{0})
+	h.Write([]byte(entry.Commitment))
+	for _, candidate := range entry.Ballot {
+		h.Write([]byte(":" + candidate.String()))
+	}
+	return h.Sum(nil)
+}
+
+// merkleNode hashes two children, sha3-256 of a one byte followed by the
+// hashes of the children. The prefixes keep leaves and nodes apart
+func merkleNode(left []byte, right []byte) []byte {
+	h := sha3.New256()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleLevels returns every level of the tree, from the leaves to the root.
+// A lone node at the end of a level is moved up unchanged
+func merkleLevels(leaves [][]byte) [][][]byte {
+	levels := [][][]byte{leaves}
+	for level := leaves; len(level) > 1; {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 < len(level) {
+				next = append(next, merkleNode(level[i], level[i+1]))
+			} else {
+				next = append(next, level[i])
+			}
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// merkleRoot returns the hex encoded root of the tree over the entries, which
+// is empty if there are no entries
+func merkleRoot(entries []BulletinEntry) string {
+	var leaves [][]byte
+	for _, entry := range entries {
+		leaves = append(leaves, merkleLeaf(entry))
+	}
+	levels := merkleLevels(leaves)
+	root := levels[len(levels)-1]
+	if len(root) == 0 {
+		return ""
+	}
+	return hex.EncodeToString(root[0])
+}
+
+// sortEntries orders the entries by their leaf hashes, which is the order of
+// the bulletin board and unrelated to when the ballots were cast
+func sortEntries(entries []BulletinEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return bytes.Compare(merkleLeaf(entries[i]), merkleLeaf(entries[j])) < 0
+	})
+}
+
+// Proof returns the proof that the ballot at the index is in the board
+func (b BulletinBoard) Proof(index int) MerkleProof {
+	var leaves [][]byte
+	for _, entry := range b.Ballots {
+		leaves = append(leaves, merkleLeaf(entry))
+	}
+	proof := MerkleProof{Root: b.Root, Entry: b.Ballots[index], Path: []MerkleStep{}}
+	levels := merkleLevels(leaves)
+	for _, level := range levels[:len(levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof.Path = append(proof.Path, MerkleStep{
+				Hash: hex.EncodeToString(level[sibling]),
+				Left: sibling < index,
+			})
+		}
+		index /= 2
+	}
+	return proof
+}
+
+// Verify checks that the path leads from the entry to the root
+func (p MerkleProof) Verify() bool {
+	hash := merkleLeaf(p.Entry)
+	for _, step := range p.Path {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return false
+		}
+		if step.Left {
+			hash = merkleNode(sibling, hash)
+		} else {
+			hash = merkleNode(hash, sibling)
+		}
+	}
+	return hex.EncodeToString(hash) == p.Root
+}
+
+// bulletinBoard builds the bulletin board of the election from its votes
+func bulletinBoard(repo Repository, election database.Election) (BulletinBoard, error) {
+	votes, err := repo.ListVotes(election.ID)
+	if err != nil {
+		return BulletinBoard{}, err
+	}
+	hashes, err := repo.ListVoteHashes(election.ID)
+	if err != nil {
+		return BulletinBoard{}, err
+	}
+	commitments := map[uuid.UUID]string{}
+	for _, hash := range hashes {
+		commitments[hash.VoteID] = hash.Hash
+	}
+
+	board := BulletinBoard{
+		Format:     BulletinBoardFormat,
+		Version:    BulletinBoardVersion,
+		Election:   exportedElection(election),
+		Candidates: exportedCandidates(election),
+		Ballots:    []BulletinEntry{},
+	}
+	for _, vote := range votes {
+		for n := 0; n < vote.Weight; n++ {
+			board.Ballots = append(board.Ballots, BulletinEntry{
+				Commitment: unitCommitment(commitments[vote.ID], n),
+				Ballot:     BallotOf(vote),
+			})
+		}
+	}
+	sortEntries(board.Ballots)
+	board.Root = merkleRoot(board.Ballots)
+	return board, nil
+}
+
+// BulletinBoard returns the bulletin board of a finalized election that has
+// closed. Fails if the ballots no longer match the root stored when the
+// election was finalized
+func (s *Votes) BulletinBoard(electionId uuid.UUID, now time.Time) (BulletinBoard, error) {
+	election, err := getElection(s.repo, electionId)
+	if err != nil {
+		return BulletinBoard{}, err
+	}
+	if !election.Finalized {
+		return BulletinBoard{}, ErrBoardNotPublished
+	}
+	if resultsHidden(election, now) {
+		return BulletinBoard{}, ErrResultsHidden
+	}
+	board, err := bulletinBoard(s.repo, election)
+	if err != nil {
+		return board, err
+	}
+	if election.BallotRoot != "" && board.Root != election.BallotRoot {
+		return board, fmt.Errorf("ballots of election %s do not match the root %s", election.ID, election.BallotRoot)
+	}
+	return board, nil
+}
+
+// Proof returns the proof that the ballot with the receipt commitment is
+// included in the bulletin board of a finalized election that has closed
+func (s *Votes) Proof(electionId uuid.UUID, commitment string, now time.Time) (MerkleProof, error) {
+	board, err := s.BulletinBoard(electionId, now)
+	if err != nil {
+		return MerkleProof{}, err
+	}
+	for i, entry := range board.Ballots {
+		if entry.Commitment == commitment && commitment != "" {
+			return board.Proof(i), nil
+		}
+	}
+	return MerkleProof{}, ErrUnknownReceipt
+}
+
+// ReadBulletinBoard reads a bulletin board, validating that every ballot
+// ranks all candidates and that the ballots match the root
+func ReadBulletinBoard(data []byte) (BulletinBoard, error) {
+	var board BulletinBoard
+	if err := json.Unmarshal(data, &board); err != nil {
+		return board, invalid("Malformed bulletin board: %s", err)
+	}
+	if board.Format != BulletinBoardFormat {
+		return board, invalid("Not a bulletin board, format is '%s'", board.Format)
+	}
+	if board.Version != BulletinBoardVersion {
+		return board, invalid("Unsupported bulletin board version %d", board.Version)
+	}
+	if root := merkleRoot(board.Ballots); root != board.Root {
+		return board, invalid("The ballots do not match the root %s, they give %s", board.Root, root)
+	}
+	return board, nil
+}
+
+// BallotExport returns the ballots of the board as an export, which can be
+// counted
+func (b BulletinBoard) BallotExport() BallotExport {
+	export := BallotExport{
+		Format:     BallotExportFormat,
+		Version:    BallotExportVersion,
+		Election:   b.Election,
+		Candidates: b.Candidates,
+	}
+	for _, entry := range b.Ballots {
+		export.Ballots = append(export.Ballots, entry.Ballot)
+	}
+	return export
+}
@@ -0,0 +1,157 @@
+package service_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/service"
+	"durn/server/service/servicetest"
+)
+
+func TestBulletinBoard(t *testing.T) {
+	// Trees of all sizes up to a few levels, to cover lone nodes
+	for count := 1; count <= 9; count++ {
+		t.Run(fmt.Sprintf("%d ballots", count), func(t *testing.T) {
+			repo := servicetest.NewRepository(t)
+			elections := service.NewElections(repo)
+			votes := service.NewVotes(repo)
+			election := createElection(t, elections, "Alice", "Bob")
+
+			ballot := ballotOf(election.Candidates)
+			var receipts []service.Receipt
+			for i := 0; i < count; i++ {
+				b := service.Ballot{ballot[i%3], ballot[(i+1)%3], ballot[(i+2)%3]}
+				receipt, err := votes.Cast(election.ID, fmt.Sprintf("%d@kth.se", i), "", b, openTime.Add(time.Hour))
+				if err != nil {
+					t.Fatal(err)
+				}
+				receipts = append(receipts, receipt)
+			}
+
+			if _, err := votes.BulletinBoard(election.ID, afterClose); !errors.Is(err, service.ErrBoardNotPublished) {
+				t.Errorf("BulletinBoard() before finalizing = %v, want ErrBoardNotPublished", err)
+			}
+			finalized, err := elections.Finalize(election.ID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			board, err := votes.BulletinBoard(election.ID, afterClose)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if board.Root == "" || board.Root != finalized.BallotRoot {
+				t.Errorf("board root = %q, want the stored root %q", board.Root, finalized.BallotRoot)
+			}
+			if len(board.Ballots) != count {
+				t.Errorf("board has %d ballots, want %d", len(board.Ballots), count)
+			}
+
+			for _, receipt := range receipts {
+				proof, err := votes.Proof(election.ID, receipt.Commitment, afterClose)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !proof.Verify() {
+					t.Errorf("proof of %s did not verify: %+v", receipt.Commitment, proof)
+				}
+				proof.Entry.Ballot = service.Ballot{proof.Entry.Ballot[1], proof.Entry.Ballot[0], proof.Entry.Ballot[2]}
+				if proof.Verify() {
+					t.Errorf("proof of %s verified with another ballot", receipt.Commitment)
+				}
+			}
+			if _, err := votes.Proof(election.ID, "unknown", afterClose); !errors.Is(err, service.ErrUnknownReceipt) {
+				t.Errorf("Proof() of unknown receipt = %v, want ErrUnknownReceipt", err)
+			}
+		})
+	}
+}
+
+func TestBulletinBoardRecount(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob")
+
+	ballot := ballotOf(election.Candidates)
+	for i, b := range []service.Ballot{
+		{ballot[1], ballot[2], ballot[0]},
+		{ballot[2], ballot[1], ballot[0]},
+		{ballot[1], ballot[0], ballot[2]},
+	} {
+		if _, err := votes.Cast(election.ID, fmt.Sprintf("%d@kth.se", i), "", b, openTime.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := elections.Finalize(election.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	board, err := votes.BulletinBoard(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	export, err := service.ReadBallotExport(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	official, err := votes.CountSchulze(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recount := service.Schulze(export.CandidateList(), export.Ballots, export.Weights)
+	if !reflect.DeepEqual(recount.VoteMatrix, official.VoteMatrix) {
+		t.Errorf("recount = %+v, want %+v", recount, official)
+	}
+
+	// A board where a ballot was changed does not match its root
+	tampered := board
+	tampered.Ballots = append([]service.BulletinEntry{}, board.Ballots...)
+	tampered.Ballots[0].Ballot = service.Ballot{ballot[0], ballot[1], ballot[2]}
+	data, err = json.Marshal(tampered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.ReadBallotExport(bytes.NewReader(data)); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("ReadBallotExport() of tampered board = %v, want ErrInvalid", err)
+	}
+}
+
+func TestBulletinBoardDetectsChanges(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob")
+	ballot := ballotOf(election.Candidates)
+	if _, err := votes.Cast(election.ID, "a@kth.se", "", ballot, openTime.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := elections.Finalize(election.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Change the ballot in the database after the root was stored
+	stored, err := repo.ListVotes(election.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ReplaceRankings(stored[0].ID, []database.Ranking{
+		{VoteID: stored[0].ID, Rank: 0, CandidateID: ballot[2]},
+		{VoteID: stored[0].ID, Rank: 1, CandidateID: ballot[1]},
+		{VoteID: stored[0].ID, Rank: 2, CandidateID: ballot[0]},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := votes.BulletinBoard(election.ID, afterClose); err == nil {
+		t.Error("BulletinBoard() succeeded although the ballots do not match the root")
+	}
+}
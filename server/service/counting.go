@@ -0,0 +1,315 @@
+package service
+
+import (
+	"math/rand"
+	"sort"
+
+	database "durn/server/db"
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Ballot is the ranking of a single vote, ordered from highest to lowest
+// priority
+type Ballot []uuid.UUID
+
+// BallotOf returns the ranking of a vote
+func BallotOf(vote database.Vote) Ballot {
+	ballot := make(Ballot, len(vote.Rankings))
+	for _, ranking := range vote.Rankings {
+		ballot[ranking.Rank] = ranking.CandidateID
+	}
+	return ballot
+}
+
+// ValidateBallot checks that a ballot ranks every candidate exactly once,
+// and contains no other candidates
+func ValidateBallot(candidates []database.Candidate, ballot Ballot) error {
+	var electionCandidates []uuid.UUID
+	for _, candidate := range candidates {
+		electionCandidates = append(electionCandidates, candidate.ID)
+	}
+	if !util.SameSet(electionCandidates, ballot) {
+		return ErrInvalidBallot
+	}
+	return nil
+}
+
+// RemoveWithdrawn removes withdrawn candidates from the candidates and the
+// ballots, so that they are counted as though they were never on the ballot
+func RemoveWithdrawn(candidates []database.Candidate, ballots []Ballot) ([]database.Candidate, []Ballot) {
+	withdrawn := map[uuid.UUID]bool{}
+	var running []database.Candidate
+	for _, candidate := range candidates {
+		if candidate.Withdrawn {
+			withdrawn[candidate.ID] = true
+		} else {
+			running = append(running, candidate)
+		}
+	}
+	if len(withdrawn) == 0 {
+		return candidates, ballots
+	}
+
+	result := make([]Ballot, len(ballots))
+	for i, ballot := range ballots {
+		for _, candidate := range ballot {
+			if !withdrawn[candidate] {
+				result[i] = append(result[i], candidate)
+			}
+		}
+	}
+	return running, result
+}
+
+// weightOf returns the weight of the i:th ballot, which is 1 for all ballots
+// when there are no weights
+func weightOf(weights []int, i int) int {
+	if weights == nil {
+		return 1
+	}
+	return weights[i]
+}
+
+// isBlank checks if the candidate is the symbolic candidate for a blank vote
+func isBlank(candidate database.Candidate) bool {
+	return candidate.Symbolic && candidate.SymbolicKind == database.SymbolicBlank
+}
+
+// SchulzeResult is the result of counting an election with the Schulze method.
+// The rows and columns of the matrices are ordered as the ranking, and count
+// weighted votes. Blanks is the amount of ballots that ranked the blank vote
+// first. TotalVotes and Blanks count ballots, while their weighted
+// counterparts sum the weights of the ballots. Validity is only known when
+// counting an election, not when recounting exported ballots
+type SchulzeResult struct {
+	Ranking        []database.Candidate `json:"ranking"`
+	TotalVotes     int                  `json:"totalVotes"`
+	WeightedVotes  int                  `json:"weightedVotes"`
+	Blanks         int                  `json:"blanks"`
+	WeightedBlanks int                  `json:"weightedBlanks"`
+	VoteMatrix     [][]int              `json:"voteMatrix"`
+	SchultzeMatrix [][]int              `json:"schultzeMatrix"`
+	Validity       *Validity            `json:"validity,omitempty"`
+}
+
+// Schulze counts ballots according to the schultze method, where each ballot
+// counts as its weight in weights. Without weights every ballot counts as
+// one. Ties are broken randomly. Symbolic candidates are counted by their kind:
+//   - vacant and reopen are ranked like any other candidate, so candidates
+//     ranked below them should not be elected
+//   - blank is not ranked. The candidates a ballot ranks after blank are
+//     treated as unranked, i.e. equally preferred, so a ballot with blank
+//     first expresses no preferences at all
+//
+// https://en.wikipedia.org/wiki/Schulze_method
+func Schulze(candidates []database.Candidate, ballots []Ballot, weights []int) SchulzeResult {
+	blank := map[uuid.UUID]bool{}
+	var ranked []database.Candidate
+	for _, candidate := range candidates {
+		if isBlank(candidate) {
+			blank[candidate.ID] = true
+		} else {
+			ranked = append(ranked, candidate)
+		}
+	}
+	candidates = ranked
+
+	candidateIndexes := make(map[uuid.UUID]int)
+	N := len(candidates)
+
+	// prefer[i][j] is the weighted amount of voters that prefer candidate i to candidate j
+	prefer := make([][]int, N)
+
+	for idx, candidate := range candidates {
+		candidateIndexes[candidate.ID] = idx
+		prefer[idx] = make([]int, N)
+	}
+
+	blanks := 0
+	weightedBlanks := 0
+	weightedVotes := 0
+	for n, ballot := range ballots {
+		weight := weightOf(weights, n)
+		weightedVotes += weight
+		for i, a := range ballot {
+			if blank[a] {
+				if i == 0 {
+					blanks++
+					weightedBlanks += weight
+				}
+				break
+			}
+			for _, b := range ballot[i+1:] {
+				if blank[b] {
+					continue
+				}
+				aIdx := candidateIndexes[a]
+				bIdx := candidateIndexes[b]
+				prefer[aIdx][bIdx] += weight
+			}
+		}
+	}
+
+	p := StrongestPaths(prefer)
+
+	result := make([]int, N)
+	for i := range prefer {
+		result[i] = i
+	}
+	// Since we use stable sort, shuffling before sorting is equivalent to randomizing the order
+	// between candidates which were tied
+	rand.Shuffle(len(result), func(i, j int) {
+		result[i], result[j] = result[j], result[i]
+	})
+	sort.SliceStable(result, func(i, j int) bool {
+		a := result[i]
+		b := result[j]
+		return p[a][b] >= p[b][a]
+	})
+
+	var ret SchulzeResult
+	ret.TotalVotes = len(ballots)
+	ret.WeightedVotes = weightedVotes
+	ret.Blanks = blanks
+	ret.WeightedBlanks = weightedBlanks
+
+	for _, idx := range result {
+		ret.Ranking = append(ret.Ranking, candidates[idx])
+		var votesRow []int
+		var schultzeRow []int
+		for _, idx2 := range result {
+			votesRow = append(votesRow, prefer[idx][idx2])
+			schultzeRow = append(schultzeRow, p[idx][idx2])
+		}
+		ret.VoteMatrix = append(ret.VoteMatrix, votesRow)
+		ret.SchultzeMatrix = append(ret.SchultzeMatrix, schultzeRow)
+	}
+
+	return ret
+}
+
+// StrongestPaths calculates the strength of the strongest path between all
+// pairs of candidates, given the matrix of pairwise preferences
+func StrongestPaths(E [][]int) [][]int {
+	res := util.Copy2DSlice(E)
+	for k := range E {
+		for i := range E {
+			for j := range E {
+				if i != j && j != k && k != i {
+					res[i][j] = util.Max(
+						res[i][j],
+						util.Min(res[i][k], res[k][j]),
+					)
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+// IRVCandidateResult is the votes of a candidate in a round of instant runoff
+// voting. Votes counts ballots and WeightedVotes sums their weights
+type IRVCandidateResult struct {
+	Name          string `json:"name"`
+	Votes         int    `json:"votes"`
+	WeightedVotes int    `json:"weightedVotes"`
+	Eliminated    bool   `json:"eliminated"`
+}
+
+// IRVStage is the result of a single round of instant runoff voting
+type IRVStage struct {
+	Candidates     []IRVCandidateResult `json:"candidates"`
+	Blanks         int                  `json:"blanks"`
+	WeightedBlanks int                  `json:"weightedBlanks"`
+}
+
+// IRV counts ballots using the "Alternativsomröstning" algorithm,
+// as described in https://styrdokument.datasektionen.se/reglemente (§3.12.7 Urnval)
+// Each ballot counts as its weight in weights, or as one without weights, and
+// candidates are eliminated and elected by their weighted votes.
+// Symbolic candidates are counted by their kind:
+//   - vacant is never eliminated, and the post is left vacant if it wins
+//   - reopen is counted and eliminated like any other candidate, and the
+//     nominations are re-opened if it wins
+//   - blank votes are reported as Blanks and do not count towards the
+//     majority. Blank is never eliminated, so the rest of a ballot that
+//     reaches it is not counted
+//
+// Does not handle the case where the two lowest candidates have the same amount of votes
+// in a good way (it is probably random) since it is not handled in the algorithm specification
+func IRV(candidates []database.Candidate, ballots []Ballot, weights []int) []IRVStage {
+	candidateEliminated := make(map[uuid.UUID]bool)
+	candidateNames := make(map[uuid.UUID]string)
+	blank := make(map[uuid.UUID]bool)
+	vacant := make(map[uuid.UUID]bool)
+	for _, candidate := range candidates {
+		candidateNames[candidate.ID] = candidate.Name
+		candidateEliminated[candidate.ID] = false
+		blank[candidate.ID] = isBlank(candidate)
+		vacant[candidate.ID] = candidate.Symbolic && candidate.SymbolicKind == database.SymbolicVacant
+	}
+
+	var electionResult []IRVStage
+
+	for {
+		var stageResult IRVStage
+		count := make(map[uuid.UUID]int)
+		raw := make(map[uuid.UUID]int)
+
+		for i, vote := range ballots {
+			for _, candidate := range vote {
+				if !candidateEliminated[candidate] {
+					count[candidate] += weightOf(weights, i)
+					raw[candidate] += 1
+					break
+				}
+			}
+		}
+
+		var eliminate uuid.UUID
+		chosenElimination := false
+		total := 0
+		for candidate, votes := range count {
+			if blank[candidate] {
+				stageResult.Blanks = raw[candidate]
+				stageResult.WeightedBlanks = votes
+				continue
+			}
+			total += votes
+			if !vacant[candidate] {
+				if !chosenElimination || count[eliminate] > count[candidate] {
+					eliminate = candidate
+					chosenElimination = true
+				}
+			}
+		}
+		candidateEliminated[eliminate] = true
+
+		for candidate, votes := range count {
+			if blank[candidate] {
+				continue
+			}
+			stageResult.Candidates = append(stageResult.Candidates, IRVCandidateResult{
+				Name:          candidateNames[candidate],
+				Votes:         raw[candidate],
+				WeightedVotes: votes,
+				Eliminated:    candidate == eliminate,
+			})
+		}
+
+		sort.Slice(stageResult.Candidates, func(i, j int) bool {
+			return stageResult.Candidates[i].WeightedVotes > stageResult.Candidates[j].WeightedVotes
+		})
+
+		if len(stageResult.Candidates) == 0 || stageResult.Candidates[0].WeightedVotes*2 > total || !chosenElimination {
+			electionResult = append(electionResult, stageResult)
+			break
+		}
+		electionResult = append(electionResult, stageResult)
+	}
+
+	return electionResult
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	database "durn/server/db"
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// makeCandidates creates candidates with the given names
+func makeCandidates(names ...string) []database.Candidate {
+	var candidates []database.Candidate
+	for _, name := range names {
+		candidates = append(candidates, database.Candidate{ID: uuid.NewV4(), Name: name})
+	}
+	return candidates
+}
+
+// makeBallots creates count copies of a ballot ranking the candidates in the
+// order given by the first letter of their names, e.g. "ACBED"
+func makeBallots(candidates []database.Candidate, count int, order string) []Ballot {
+	byName := map[byte]uuid.UUID{}
+	for _, candidate := range candidates {
+		byName[candidate.Name[0]] = candidate.ID
+	}
+	var ballots []Ballot
+	for i := 0; i < count; i++ {
+		var ballot Ballot
+		for j := range order {
+			ballot = append(ballot, byName[order[j]])
+		}
+		ballots = append(ballots, ballot)
+	}
+	return ballots
+}
+
+// makeSymbolic makes the candidate a symbolic candidate of the kind
+func makeSymbolic(candidate *database.Candidate, kind database.SymbolicKind) {
+	candidate.Symbolic = true
+	candidate.SymbolicKind = kind
+}
+
+func names(candidates []database.Candidate) []string {
+	var res []string
+	for _, candidate := range candidates {
+		res = append(res, candidate.Name)
+	}
+	return res
+}
+
+// The example from https://en.wikipedia.org/wiki/Schulze_method
+var wikipediaBallots = []struct {
+	count int
+	order string
+}{
+	{5, "ACBED"},
+	{5, "ADECB"},
+	{8, "BEDAC"},
+	{3, "CABED"},
+	{7, "CAEBD"},
+	{2, "CBADE"},
+	{7, "DCEBA"},
+	{8, "EBADC"},
+}
+
+var wikipediaPreferences = [][]int{
+	{0, 20, 26, 30, 22},
+	{25, 0, 16, 33, 18},
+	{19, 29, 0, 17, 24},
+	{15, 12, 28, 0, 14},
+	{23, 27, 21, 31, 0},
+}
+
+var wikipediaStrongestPaths = [][]int{
+	{0, 28, 28, 30, 24},
+	{25, 0, 28, 33, 24},
+	{25, 29, 0, 29, 24},
+	{25, 28, 28, 0, 24},
+	{25, 28, 28, 31, 0},
+}
+
+func TestStrongestPaths(t *testing.T) {
+	got := StrongestPaths(wikipediaPreferences)
+	if !reflect.DeepEqual(got, wikipediaStrongestPaths) {
+		t.Errorf("StrongestPaths() = %v, want %v", got, wikipediaStrongestPaths)
+	}
+	if wikipediaPreferences[0][1] != 20 {
+		t.Error("StrongestPaths() modified its input")
+	}
+}
+
+func TestSchulzeWikipediaExample(t *testing.T) {
+	candidates := makeCandidates("A", "B", "C", "D", "E")
+	var ballots []Ballot
+	for _, b := range wikipediaBallots {
+		ballots = append(ballots, makeBallots(candidates, b.count, b.order)...)
+	}
+
+	// Run several times since candidates are shuffled before sorting
+	for i := 0; i < 20; i++ {
+		result := Schulze(candidates, ballots, nil)
+
+		if result.TotalVotes != 45 {
+			t.Errorf("TotalVotes = %d, want 45", result.TotalVotes)
+		}
+		if got, want := names(result.Ranking), []string{"E", "A", "C", "B", "D"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Ranking = %v, want %v", got, want)
+		}
+
+		// The matrices are ordered as the ranking, E A C B D
+		order := []int{4, 0, 2, 1, 3}
+		for i, a := range order {
+			for j, b := range order {
+				if result.VoteMatrix[i][j] != wikipediaPreferences[a][b] {
+					t.Errorf("VoteMatrix[%d][%d] = %d, want %d", i, j, result.VoteMatrix[i][j], wikipediaPreferences[a][b])
+				}
+				if result.SchultzeMatrix[i][j] != wikipediaStrongestPaths[a][b] {
+					t.Errorf("SchultzeMatrix[%d][%d] = %d, want %d", i, j, result.SchultzeMatrix[i][j], wikipediaStrongestPaths[a][b])
+				}
+			}
+		}
+	}
+}
+
+func TestSchulzeTie(t *testing.T) {
+	candidates := makeCandidates("A", "B", "C")
+	ballots := append(makeBallots(candidates, 2, "ABC"), makeBallots(candidates, 2, "BAC")...)
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		ranking := names(Schulze(candidates, ballots, nil).Ranking)
+		if ranking[2] != "C" {
+			t.Fatalf("Ranking = %v, want C last", ranking)
+		}
+		seen[ranking[0]] = true
+	}
+	if !seen["A"] || !seen["B"] {
+		t.Errorf("tie between A and B was not broken randomly, winners seen: %v", seen)
+	}
+}
+
+func TestIRV(t *testing.T) {
+	candidates := makeCandidates("Alice", "Bob", "Carol", util.VacantCandidate, util.BlankCandidate)
+	makeSymbolic(&candidates[3], database.SymbolicVacant)
+	makeSymbolic(&candidates[4], database.SymbolicBlank)
+	alice, bob, carol, vacant, blank := candidates[0].ID, candidates[1].ID, candidates[2].ID, candidates[3].ID, candidates[4].ID
+
+	// Alice: 4, Bob: 4, Carol: 2, Vakant: 1, Blank: 1
+	// Carol is eliminated first, and her votes go to Bob who then has a majority
+	var ballots []Ballot
+	add := func(count int, ballot Ballot) {
+		for i := 0; i < count; i++ {
+			ballots = append(ballots, ballot)
+		}
+	}
+	add(4, Ballot{alice, bob, carol, vacant, blank})
+	add(4, Ballot{bob, alice, carol, vacant, blank})
+	add(2, Ballot{carol, bob, alice, vacant, blank})
+	add(1, Ballot{vacant, alice, bob, carol, blank})
+	add(1, Ballot{blank, alice, bob, carol, vacant})
+
+	stages := IRV(candidates, ballots, nil)
+	if len(stages) != 2 {
+		t.Fatalf("got %d stages, want 2: %+v", len(stages), stages)
+	}
+
+	first := stages[0]
+	if first.Blanks != 1 {
+		t.Errorf("first stage Blanks = %d, want 1", first.Blanks)
+	}
+	votes := map[string]int{}
+	for _, candidate := range first.Candidates {
+		votes[candidate.Name] = candidate.Votes
+		if candidate.Eliminated != (candidate.Name == "Carol") {
+			t.Errorf("first stage %s Eliminated = %v", candidate.Name, candidate.Eliminated)
+		}
+	}
+	wantVotes := map[string]int{"Alice": 4, "Bob": 4, "Carol": 2, util.VacantCandidate: 1}
+	if !reflect.DeepEqual(votes, wantVotes) {
+		t.Errorf("first stage votes = %v, want %v", votes, wantVotes)
+	}
+
+	winner := stages[1].Candidates[0]
+	if winner.Name != "Bob" || winner.Votes != 6 {
+		t.Errorf("winner = %+v, want Bob with 6 votes", winner)
+	}
+}
+
+func TestIRVReopen(t *testing.T) {
+	candidates := makeCandidates("Alice", "Bob", util.ReopenCandidate, util.VacantCandidate)
+	makeSymbolic(&candidates[2], database.SymbolicReopen)
+	makeSymbolic(&candidates[3], database.SymbolicVacant)
+
+	// Nyval has the fewest votes and is eliminated like any candidate, while
+	// Vakant with even fewer votes is never eliminated. Nyval's votes then
+	// give Bob a majority
+	var ballots []Ballot
+	ballots = append(ballots, makeBallots(candidates, 3, "ABNV")...)
+	ballots = append(ballots, makeBallots(candidates, 4, "BANV")...)
+	ballots = append(ballots, makeBallots(candidates, 2, "NBAV")...)
+	ballots = append(ballots, makeBallots(candidates, 1, "VABN")...)
+
+	stages := IRV(candidates, ballots, nil)
+	if len(stages) != 2 {
+		t.Fatalf("got %d stages, want 2: %+v", len(stages), stages)
+	}
+	for _, candidate := range stages[0].Candidates {
+		if candidate.Eliminated != (candidate.Name == util.ReopenCandidate) {
+			t.Errorf("first stage %s Eliminated = %v", candidate.Name, candidate.Eliminated)
+		}
+	}
+	winner := stages[1].Candidates[0]
+	if winner.Name != "Bob" || winner.Votes != 6 {
+		t.Errorf("winner = %+v, want Bob with 6 votes", winner)
+	}
+}
+
+func TestSchulzeBlank(t *testing.T) {
+	candidates := makeCandidates("Alice", "Carol", "Dave", util.BlankCandidate)
+	makeSymbolic(&candidates[3], database.SymbolicBlank)
+
+	// Candidates after Blank are unranked, so the first group prefers Alice
+	// over Carol and Dave but has no preference between them
+	var ballots []Ballot
+	ballots = append(ballots, makeBallots(candidates, 2, "ABDC")...)
+	ballots = append(ballots, makeBallots(candidates, 3, "DBAC")...)
+	ballots = append(ballots, makeBallots(candidates, 1, "BADC")...)
+
+	result := Schulze(candidates, ballots, nil)
+	if result.TotalVotes != 6 || result.Blanks != 1 {
+		t.Errorf("TotalVotes, Blanks = %d, %d, want 6, 1", result.TotalVotes, result.Blanks)
+	}
+	if ranking := names(result.Ranking); !reflect.DeepEqual(ranking, []string{"Dave", "Alice", "Carol"}) {
+		t.Errorf("Ranking = %v, want [Dave Alice Carol]", ranking)
+	}
+	want := [][]int{
+		{0, 3, 3},
+		{2, 0, 2},
+		{0, 0, 0},
+	}
+	if !reflect.DeepEqual(result.VoteMatrix, want) {
+		t.Errorf("VoteMatrix = %v, want %v", result.VoteMatrix, want)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	candidates := makeCandidates("Alice", "Dave", util.BlankCandidate)
+	makeSymbolic(&candidates[2], database.SymbolicBlank)
+
+	// Three voters prefer Alice, but the two delegates preferring Dave have
+	// weight 2 each, and the blank vote weight 3
+	var ballots []Ballot
+	ballots = append(ballots, makeBallots(candidates, 3, "ADB")...)
+	ballots = append(ballots, makeBallots(candidates, 2, "DAB")...)
+	ballots = append(ballots, makeBallots(candidates, 1, "BAD")...)
+	weights := []int{1, 1, 1, 2, 2, 3}
+
+	result := Schulze(candidates, ballots, weights)
+	if ranking := names(result.Ranking); !reflect.DeepEqual(ranking, []string{"Dave", "Alice"}) {
+		t.Errorf("Ranking = %v, want [Dave Alice]", ranking)
+	}
+	if !reflect.DeepEqual(result.VoteMatrix, [][]int{{0, 4}, {3, 0}}) {
+		t.Errorf("VoteMatrix = %v, want [[0 4] [3 0]]", result.VoteMatrix)
+	}
+	if result.TotalVotes != 6 || result.WeightedVotes != 10 || result.Blanks != 1 || result.WeightedBlanks != 3 {
+		t.Errorf("totals = %d, %d, blanks %d, %d, want 6, 10, blanks 1, 3",
+			result.TotalVotes, result.WeightedVotes, result.Blanks, result.WeightedBlanks)
+	}
+
+	stages := IRV(candidates, ballots, weights)
+	winner := stages[len(stages)-1].Candidates[0]
+	if winner.Name != "Dave" || winner.Votes != 2 || winner.WeightedVotes != 4 {
+		t.Errorf("winner = %+v, want Dave with 2 votes of weight 4", winner)
+	}
+	if stages[0].Blanks != 1 || stages[0].WeightedBlanks != 3 {
+		t.Errorf("blanks = %d, %d, want 1, 3", stages[0].Blanks, stages[0].WeightedBlanks)
+	}
+}
+
+func TestIRVNoBallots(t *testing.T) {
+	candidates := makeCandidates("Alice", "Bob")
+	stages := IRV(candidates, nil, nil)
+	if len(stages) != 1 || len(stages[0].Candidates) != 0 {
+		t.Errorf("IRV() with no ballots = %+v, want a single empty stage", stages)
+	}
+}
+
+func TestValidateBallot(t *testing.T) {
+	candidates := makeCandidates("A", "B", "C")
+	a, b, c := candidates[0].ID, candidates[1].ID, candidates[2].ID
+
+	tests := []struct {
+		name   string
+		ballot Ballot
+		valid  bool
+	}{
+		{"all candidates", Ballot{c, a, b}, true},
+		{"missing candidate", Ballot{a, b}, false},
+		{"duplicate candidate", Ballot{a, b, b}, false},
+		{"candidate ranked twice", Ballot{a, b, c, a}, false},
+		{"unknown candidate", Ballot{a, b, uuid.NewV4()}, false},
+		{"empty", Ballot{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateBallot(candidates, test.ballot)
+			if test.valid && err != nil {
+				t.Errorf("ValidateBallot() = %v, want nil", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidBallot) {
+				t.Errorf("ValidateBallot() = %v, want ErrInvalidBallot", err)
+			}
+		})
+	}
+}
+
+func TestRemoveWithdrawn(t *testing.T) {
+	candidates := makeCandidates("A", "B", "C")
+	candidates[1].Withdrawn = true
+	ballots := append(makeBallots(candidates, 3, "BCA"), makeBallots(candidates, 2, "ABC")...)
+
+	running, counted := RemoveWithdrawn(candidates, ballots)
+	if got := names(running); !reflect.DeepEqual(got, []string{"A", "C"}) {
+		t.Errorf("candidates = %v, want A and C", got)
+	}
+	for _, ballot := range counted {
+		if err := ValidateBallot(running, ballot); err != nil {
+			t.Errorf("ballot %v does not rank exactly the running candidates", ballot)
+		}
+	}
+
+	// B would win, but is withdrawn so C wins with B's votes
+	result := Schulze(running, counted, nil)
+	if got := names(result.Ranking); !reflect.DeepEqual(got, []string{"C", "A"}) {
+		t.Errorf("Ranking = %v, want C, A", got)
+	}
+	if len(ballots[0]) != 3 {
+		t.Errorf("RemoveWithdrawn() modified its input")
+	}
+}
@@ -0,0 +1,516 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Elections manages elections and their candidates
+type Elections struct {
+	repo Repository
+}
+
+func NewElections(repo Repository) *Elections {
+	return &Elections{repo: repo}
+}
+
+// ElectionParams are the fields that can be set when creating an election
+type ElectionParams struct {
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	OpenTime      util.NullTime `json:"openTime"`
+	CloseTime     util.NullTime `json:"closeTime"`
+	Mandates      int           `json:"mandates"`
+	ExtraMandates int           `json:"extraMandates"`
+	// ShuffleCandidates shows the candidates in a random order for each
+	// voter, instead of by their position
+	ShuffleCandidates bool `json:"shuffleCandidates"`
+	// Translations of the name and description, by locale
+	Translations database.Translations `json:"translations"`
+	// SymbolicCandidates are the kinds of symbolic candidates on the ballot
+	SymbolicCandidates []database.SymbolicKind `json:"symbolicCandidates"`
+	ValidityRules
+}
+
+// DefaultElectionParams returns the values used for fields omitted when
+// creating an election
+func DefaultElectionParams() ElectionParams {
+	return ElectionParams{
+		Name:               "",
+		Description:        "",
+		OpenTime:           util.NullTime{Valid: false},
+		CloseTime:          util.NullTime{Valid: false},
+		Mandates:           1,
+		ExtraMandates:      0,
+		ShuffleCandidates:  false,
+		SymbolicCandidates: []database.SymbolicKind{database.SymbolicVacant},
+	}
+}
+
+// ElectionChanges are the fields that can be changed on an existing
+// election. Nil fields are left unchanged
+type ElectionChanges struct {
+	Name              *string        `json:"name"`
+	Description       *string        `json:"description"`
+	OpenTime          *util.NullTime `json:"openTime"`
+	CloseTime         *util.NullTime `json:"closeTime"`
+	Mandates          *int           `json:"mandates"`
+	ExtraMandates     *int           `json:"extraMandates"`
+	ShuffleCandidates *bool          `json:"shuffleCandidates"`
+	// Translations replace all previous translations
+	Translations     *database.Translations `json:"translations"`
+	MinTurnout       *int                   `json:"minTurnout"`
+	MinTurnoutShare  *float64               `json:"minTurnoutShare"`
+	MinNonBlankShare *float64               `json:"minNonBlankShare"`
+}
+
+// CandidateParams are the fields that can be set when adding a candidate
+type CandidateParams struct {
+	Name         string `json:"name" binding:"required"`
+	Presentation string `json:"presentation"`
+	// Position is the place of the candidate on the ballot, 0 places it
+	// after all other candidates
+	Position int            `json:"position"`
+	Links    database.Links `json:"links"`
+	// Translations of the presentation, by locale
+	Translations database.Translations `json:"translations"`
+}
+
+// CandidateChanges are the fields that can be changed on an existing
+// candidate. Nil fields are left unchanged
+type CandidateChanges struct {
+	Name         *string `json:"name"`
+	Presentation *string `json:"presentation"`
+	Position     *int    `json:"position"`
+	// Withdrawn candidates stay on the ballot but are skipped when counting
+	Withdrawn    *bool                  `json:"withdrawn"`
+	Links        *database.Links        `json:"links"`
+	Translations *database.Translations `json:"translations"`
+}
+
+// getElection fetches an election, converting a missing election to ErrInvalidElection
+func getElection(repo Repository, id uuid.UUID) (database.Election, error) {
+	election, err := repo.GetElection(id)
+	if errors.Is(err, ErrNotFound) {
+		return election, ErrInvalidElection
+	}
+	return election, err
+}
+
+// Create creates an election together with its symbolic candidates
+func (s *Elections) Create(params ElectionParams) (database.Election, error) {
+	if err := validateElectionTranslations(params.Translations); err != nil {
+		return database.Election{}, err
+	}
+	if err := validateSymbolicKinds(params.SymbolicCandidates); err != nil {
+		return database.Election{}, err
+	}
+	if err := validateValidityRules(params.ValidityRules); err != nil {
+		return database.Election{}, err
+	}
+	election := newElection(params)
+	if err := s.repo.CreateElection(&election); err != nil {
+		return election, err
+	}
+	return election, nil
+}
+
+// newElection creates an unsaved election with its symbolic candidates
+func newElection(params ElectionParams) database.Election {
+	election := database.Election{
+		ID:                uuid.NewV4(),
+		Name:              params.Name,
+		Description:       params.Description,
+		Mandates:          params.Mandates,
+		ExtraMandates:     params.ExtraMandates,
+		OpenTime:          util.ConvertNullTime(params.OpenTime),
+		CloseTime:         util.ConvertNullTime(params.CloseTime),
+		Published:         false,
+		Finalized:         false,
+		ShuffleCandidates: params.ShuffleCandidates,
+		Translations:      params.Translations,
+		MinTurnout:        params.MinTurnout,
+		MinTurnoutShare:   params.MinTurnoutShare,
+		MinNonBlankShare:  params.MinNonBlankShare,
+	}
+	for _, kind := range params.SymbolicCandidates {
+		election.Candidates = append(election.Candidates, newSymbolicCandidate(election.ID, kind))
+	}
+	return election
+}
+
+// Edit updates the specified election. Finalized elections can't be edited,
+// and the close time can't be changed once votes have been cast
+func (s *Elections) Edit(id uuid.UUID, changes ElectionChanges) (database.Election, error) {
+	election, err := getElection(s.repo, id)
+	if err != nil {
+		return election, err
+	}
+
+	if election.Finalized {
+		return election, invalid("Can't edit finalized election")
+	}
+
+	if changes.Name != nil {
+		election.Name = *changes.Name
+	}
+	if changes.Description != nil {
+		election.Description = *changes.Description
+	}
+	if changes.OpenTime != nil {
+		election.OpenTime = util.ConvertNullTime(*changes.OpenTime)
+	}
+	if changes.CloseTime != nil {
+		closeTime := util.ConvertNullTime(*changes.CloseTime)
+		if closeTime.Valid != election.CloseTime.Valid || !closeTime.Time.Equal(election.CloseTime.Time) {
+			// Moving the close time would decide when the results can be
+			// seen, so it is only allowed before anyone has voted
+			if err := s.checkNoVotes(id, "the close time"); err != nil {
+				return election, err
+			}
+		}
+		election.CloseTime = closeTime
+	}
+	if changes.Mandates != nil {
+		election.Mandates = *changes.Mandates
+	}
+	if changes.ExtraMandates != nil {
+		election.ExtraMandates = *changes.ExtraMandates
+	}
+	if changes.ShuffleCandidates != nil {
+		election.ShuffleCandidates = *changes.ShuffleCandidates
+	}
+	if changes.Translations != nil {
+		if err := validateElectionTranslations(*changes.Translations); err != nil {
+			return election, err
+		}
+		election.Translations = *changes.Translations
+	}
+	rules := ValidityRules{
+		MinTurnout:       election.MinTurnout,
+		MinTurnoutShare:  election.MinTurnoutShare,
+		MinNonBlankShare: election.MinNonBlankShare,
+	}
+	if changes.MinTurnout != nil {
+		election.MinTurnout = *changes.MinTurnout
+	}
+	if changes.MinTurnoutShare != nil {
+		election.MinTurnoutShare = *changes.MinTurnoutShare
+	}
+	if changes.MinNonBlankShare != nil {
+		election.MinNonBlankShare = *changes.MinNonBlankShare
+	}
+	changedRules := ValidityRules{
+		MinTurnout:       election.MinTurnout,
+		MinTurnoutShare:  election.MinTurnoutShare,
+		MinNonBlankShare: election.MinNonBlankShare,
+	}
+	if changedRules != rules {
+		// Choosing the rules after seeing the turnout would decide whether
+		// the election is void, so they are fixed once anyone has voted
+		if err := s.checkNoVotes(id, "the validity rules"); err != nil {
+			return election, err
+		}
+	}
+	if err := validateValidityRules(changedRules); err != nil {
+		return election, err
+	}
+	if err := s.repo.SaveElection(&election); err != nil {
+		return election, err
+	}
+	return election, nil
+}
+
+// checkNoVotes returns ErrInvalid if the election has votes, saying that what
+// can't be changed
+func (s *Elections) checkNoVotes(id uuid.UUID, what string) error {
+	count, err := s.repo.CountVotes(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return invalid("Can't change %s of an election with votes", what)
+	}
+	return nil
+}
+
+// SetPublished sets the published status of the specified election
+func (s *Elections) SetPublished(id uuid.UUID, published bool) (database.Election, error) {
+	election, err := getElection(s.repo, id)
+	if err != nil {
+		return election, err
+	}
+
+	election.Published = published
+	if err := s.repo.SaveElection(&election); err != nil {
+		return election, err
+	}
+	return election, nil
+}
+
+// Finalize marks the specified election as finalized, meaning that voting
+// is finished and enabling vote counting. Encrypted ballots are decrypted
+// with the key shares, which are ignored for elections without encryption and
+// never stored.
+// The root of the bulletin board is stored, so that later changes to the
+// ballots are detected, together with the size of the voter roll, which the
+// turnout is compared to by the validity rules
+func (s *Elections) Finalize(id uuid.UUID, shares []string) (database.Election, error) {
+	election, err := getElection(s.repo, id)
+	if err != nil {
+		return election, err
+	}
+	if election.Finalized {
+		return election, nil
+	}
+	var privateKey *[32]byte
+	if election.EncryptionKey != "" {
+		if privateKey, err = electionPrivateKey(election, shares); err != nil {
+			return election, err
+		}
+		// The private key only exists while the ballots are decrypted
+		defer zero(privateKey[:])
+	}
+
+	err = s.repo.Transaction(func(repo Repository) error {
+		if privateKey != nil {
+			if err := decryptBallots(repo, election, privateKey); err != nil {
+				return err
+			}
+		}
+		board, err := bulletinBoard(repo, election)
+		if err != nil {
+			return err
+		}
+		voters, err := repo.ListVoters()
+		if err != nil {
+			return err
+		}
+		election.BallotRoot = board.Root
+		election.Electorate = len(voters)
+		election.Finalized = true
+		return repo.SaveElection(&election)
+	})
+	if err != nil {
+		return database.Election{}, err
+	}
+	return election, nil
+}
+
+// Delete removes the specified election together with its candidates
+func (s *Elections) Delete(id uuid.UUID) error {
+	if _, err := getElection(s.repo, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteElection(id)
+}
+
+// DeleteAll removes all elections, candidates and votes
+func (s *Elections) DeleteAll() error {
+	return s.repo.DeleteAllElections()
+}
+
+// Get fetches an election including its candidates
+func (s *Elections) Get(id uuid.UUID) (database.Election, error) {
+	return getElection(s.repo, id)
+}
+
+// List fetches all elections including their candidates
+func (s *Elections) List() ([]database.Election, error) {
+	return s.repo.ListElections()
+}
+
+// ListOpen fetches all elections that are open for voting at the given time
+func (s *Elections) ListOpen(now time.Time) ([]database.Election, error) {
+	elections, err := s.repo.ListElections()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []database.Election{}
+	for _, election := range elections {
+		if util.TimeIsInValidInterval(now, election.OpenTime, election.CloseTime) {
+			result = append(result, election)
+		}
+	}
+	return result, nil
+}
+
+// AddCandidate adds a candidate to the specified election.
+// Candidates can not be added to elections after they have been published,
+// opened or received votes, and the names of symbolic candidates are reserved
+func (s *Elections) AddCandidate(electionId uuid.UUID, params CandidateParams, now time.Time) (database.Candidate, error) {
+	candidate := database.Candidate{
+		ID:           uuid.NewV4(),
+		Name:         params.Name,
+		Presentation: params.Presentation,
+		ElectionID:   electionId,
+		Symbolic:     false,
+		Position:     params.Position,
+		Links:        params.Links,
+		Translations: params.Translations,
+	}
+
+	if err := validateCandidateName(params.Name); err != nil {
+		return candidate, err
+	}
+	if err := validateLinks(params.Links); err != nil {
+		return candidate, err
+	}
+	if err := validateCandidateTranslations(params.Translations); err != nil {
+		return candidate, err
+	}
+
+	election, err := getElection(s.repo, electionId)
+	if err != nil {
+		return candidate, err
+	}
+
+	if election.Published || election.Finalized {
+		return candidate, invalid("Can't add candidate to published or finalized Election")
+	}
+
+	if election.OpenTime.Valid && now.After(election.OpenTime.Time) {
+		return candidate, invalid("Can't add candidate to opened Election")
+	}
+
+	votes, err := s.repo.CountVotes(electionId)
+	if err != nil {
+		return candidate, err
+	}
+	if votes > 0 {
+		return candidate, invalid("Can't add candidate to election with votes")
+	}
+
+	if candidate.Position == 0 {
+		candidate.Position = nextPosition(election.Candidates)
+	}
+
+	if err := s.repo.CreateCandidate(&candidate); err != nil {
+		return candidate, err
+	}
+	return candidate, nil
+}
+
+// validateCandidateName checks that the name is not the name of a symbolic
+// candidate in any locale, so that voters can't confuse them
+func validateCandidateName(name string) error {
+	for _, kind := range database.SymbolicKinds {
+		for _, locale := range util.Locales {
+			if strings.EqualFold(strings.TrimSpace(name), kind.Name(locale)) {
+				return invalid("'%s' is a reserved candidate name", name)
+			}
+		}
+	}
+	return nil
+}
+
+// maxLinks is the largest amount of links a candidate can have
+const maxLinks = 10
+
+// validateLinks checks that the links of a candidate have a title and an
+// absolute http or https url
+func validateLinks(links database.Links) error {
+	if len(links) > maxLinks {
+		return invalid("A candidate can have at most %d links", maxLinks)
+	}
+	for _, link := range links {
+		if strings.TrimSpace(link.Title) == "" {
+			return invalid("Missing title of link")
+		}
+		u, err := url.Parse(link.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return invalid("Invalid link '%s', links must be http or https urls", link.URL)
+		}
+	}
+	return nil
+}
+
+// getCandidate fetches a candidate, converting a missing candidate to ErrInvalidCandidate
+func getCandidate(repo Repository, id uuid.UUID) (database.Candidate, error) {
+	candidate, err := repo.GetCandidate(id)
+	if errors.Is(err, ErrNotFound) {
+		return candidate, ErrInvalidCandidate
+	}
+	return candidate, err
+}
+
+// EditCandidate modifies the specified candidate. Candidates can be withdrawn,
+// or reinstated, at any time until the election is finalized
+func (s *Elections) EditCandidate(id uuid.UUID, changes CandidateChanges) (database.Candidate, error) {
+	candidate, err := getCandidate(s.repo, id)
+	if err != nil {
+		return candidate, err
+	}
+
+	if changes.Name != nil {
+		candidate.Name = *changes.Name
+	}
+	if changes.Presentation != nil {
+		candidate.Presentation = *changes.Presentation
+	}
+	if changes.Position != nil {
+		candidate.Position = *changes.Position
+	}
+	if changes.Links != nil {
+		if err := validateLinks(*changes.Links); err != nil {
+			return candidate, err
+		}
+		candidate.Links = *changes.Links
+	}
+	if changes.Translations != nil {
+		if err := validateCandidateTranslations(*changes.Translations); err != nil {
+			return candidate, err
+		}
+		candidate.Translations = *changes.Translations
+	}
+	if changes.Withdrawn != nil && *changes.Withdrawn != candidate.Withdrawn {
+		if candidate.Symbolic {
+			return candidate, invalid("Symbolic candidates can't be withdrawn")
+		}
+		if candidate.Election.Finalized {
+			return candidate, invalid("Can't withdraw candidate from finalized election")
+		}
+		candidate.Withdrawn = *changes.Withdrawn
+	}
+	if err := s.repo.SaveCandidate(&candidate); err != nil {
+		return candidate, err
+	}
+	return candidate, nil
+}
+
+// RemoveCandidate removes the specified candidate from its election,
+// provided that the election is not opened, finalized, or has any votes
+func (s *Elections) RemoveCandidate(id uuid.UUID, now time.Time) error {
+	candidate, err := getCandidate(s.repo, id)
+	if err != nil {
+		return err
+	}
+
+	openTime := candidate.Election.OpenTime
+
+	if openTime.Valid && now.After(openTime.Time) {
+		return invalid("Can't remove candidate from opened election")
+	}
+
+	if candidate.Election.Finalized {
+		return invalid("Can't remove candidate from finalized election")
+	}
+
+	votes, err := s.repo.CountVotes(candidate.ElectionID)
+	if err != nil {
+		return err
+	}
+	if votes > 0 {
+		return invalid("Can't remove candidate from election with votes")
+	}
+
+	return s.repo.DeleteCandidate(id)
+}
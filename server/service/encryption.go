@@ -0,0 +1,188 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	database "durn/server/db"
+
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// ElectionKeyShares are created by the election committee, outside of the
+// server. Threshold of the shares are needed to decrypt the ballots, and only
+// the public key is given to the server
+type ElectionKeyShares struct {
+	PublicKey string   `json:"publicKey"`
+	Threshold int      `json:"threshold"`
+	Shares    []string `json:"shares"`
+}
+
+// GenerateElectionKey creates a new election key and splits its private part
+// into shares, threshold of which are needed to decrypt the ballots. The
+// private key itself is forgotten. Meant to be run by the election committee
+// on their own machine, see EnableEncryption
+func GenerateElectionKey(shares int, threshold int) (ElectionKeyShares, error) {
+	if threshold < 2 || threshold > shares || shares > 255 {
+		return ElectionKeyShares{}, invalid("The threshold must be at least 2 and at most the amount of shares, which can be at most 255")
+	}
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return ElectionKeyShares{}, err
+	}
+	split, err := splitSecret(privateKey[:], shares, threshold)
+	zero(privateKey[:])
+	if err != nil {
+		return ElectionKeyShares{}, err
+	}
+
+	result := ElectionKeyShares{
+		PublicKey: base64.StdEncoding.EncodeToString(publicKey[:]),
+		Threshold: threshold,
+	}
+	for _, share := range split {
+		result.Shares = append(result.Shares, hex.EncodeToString(share))
+	}
+	return result, nil
+}
+
+// EnableEncryption makes votes in the election be stored encrypted to the
+// public key until the election is finalized. The key is created by the
+// election committee with GenerateElectionKey, and finalizing needs threshold
+// of its shares, so that nobody with fewer shares, including the operators of
+// the server, can read the ballots while voting is open. Can only be done
+// before any votes have been cast
+func (s *Elections) EnableEncryption(id uuid.UUID, publicKey string, threshold int) (database.Election, error) {
+	if _, err := decodeKey(publicKey); err != nil {
+		return database.Election{}, invalid("The public key must be 32 bytes in base64")
+	}
+	if threshold < 2 || threshold > 255 {
+		return database.Election{}, invalid("The threshold must be from 2 to 255")
+	}
+	election, err := getElection(s.repo, id)
+	if err != nil {
+		return database.Election{}, err
+	}
+	if election.Finalized {
+		return database.Election{}, invalid("Can't encrypt the ballots of a finalized election")
+	}
+	if election.EncryptionKey != "" {
+		return database.Election{}, invalid("The ballots of the election are already encrypted")
+	}
+	if count, err := s.repo.CountVotes(id); err != nil {
+		return database.Election{}, err
+	} else if count > 0 {
+		return database.Election{}, invalid("Can't encrypt the ballots of an election with votes")
+	}
+
+	election.EncryptionKey = publicKey
+	election.KeyThreshold = threshold
+	if err := s.repo.SaveElection(&election); err != nil {
+		return database.Election{}, err
+	}
+	return election, nil
+}
+
+// encryptBallot seals the ballot, the 16 bytes of each candidate id in order,
+// to the public key of the election
+func encryptBallot(election database.Election, ballot Ballot) ([]byte, error) {
+	publicKey, err := decodeKey(election.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	var plaintext []byte
+	for _, candidate := range ballot {
+		plaintext = append(plaintext, candidate.Bytes()...)
+	}
+	return box.SealAnonymous(nil, plaintext, publicKey, rand.Reader)
+}
+
+func decodeKey(encoded string) (*[32]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) != 32 {
+		return nil, fmt.Errorf("invalid election key '%s'", encoded)
+	}
+	var key [32]byte
+	copy(key[:], data)
+	return &key, nil
+}
+
+// electionPrivateKey recovers the private key of the election from the key
+// shares, checking that it belongs to the public key of the election
+func electionPrivateKey(election database.Election, shares []string) (*[32]byte, error) {
+	if len(shares) < election.KeyThreshold {
+		return nil, invalid("At least %d key shares are needed to decrypt the ballots", election.KeyThreshold)
+	}
+	var decoded [][]byte
+	for _, share := range shares {
+		data, err := hex.DecodeString(strings.TrimSpace(share))
+		if err != nil {
+			return nil, invalid("Malformed key share")
+		}
+		decoded = append(decoded, data)
+	}
+	secret, err := combineShares(decoded)
+	if err != nil {
+		return nil, invalid("Invalid key shares: %s", err)
+	}
+	defer zero(secret)
+	publicKey, err := decodeKey(election.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var privateKey, derived [32]byte
+	if len(secret) != len(privateKey) {
+		return nil, invalid("The key shares do not belong to the election")
+	}
+	copy(privateKey[:], secret)
+	curve25519.ScalarBaseMult(&derived, &privateKey)
+	if derived != *publicKey {
+		zero(privateKey[:])
+		return nil, invalid("The key shares do not belong to the election")
+	}
+	return &privateKey, nil
+}
+
+// zero overwrites key material once it is no longer needed
+func zero(key []byte) {
+	for i := range key {
+		key[i] = 0
+	}
+}
+
+// decryptBallots decrypts the encrypted ballots of the election and stores
+// them as the rankings of their votes
+func decryptBallots(repo Repository, election database.Election, privateKey *[32]byte) error {
+	publicKey, err := decodeKey(election.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	ballots, err := repo.ListEncryptedBallots(election.ID)
+	if err != nil {
+		return err
+	}
+	for _, ballot := range ballots {
+		plaintext, ok := box.OpenAnonymous(nil, ballot.Ciphertext, publicKey, privateKey)
+		if !ok || len(plaintext)%uuid.Size != 0 {
+			return fmt.Errorf("can't decrypt the ballot of vote %s", ballot.VoteID)
+		}
+		var rankings []database.Ranking
+		for rank := 0; rank*uuid.Size < len(plaintext); rank++ {
+			rankings = append(rankings, database.Ranking{
+				VoteID:      ballot.VoteID,
+				Rank:        rank,
+				CandidateID: uuid.FromBytesOrNil(plaintext[rank*uuid.Size : (rank+1)*uuid.Size]),
+			})
+		}
+		if err := repo.ReplaceRankings(ballot.VoteID, rankings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package service_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"durn/server/service"
+	"durn/server/service/servicetest"
+)
+
+func TestEncryptedBallots(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob")
+
+	keyShares, err := service.GenerateElectionKey(5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keyShares.Shares) != 5 || keyShares.Threshold != 3 {
+		t.Fatalf("got %d shares with threshold %d, want 5 and 3", len(keyShares.Shares), keyShares.Threshold)
+	}
+	encrypted, err := elections.EnableEncryption(election.ID, keyShares.PublicKey, keyShares.Threshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the public key and threshold are stored
+	if encrypted.EncryptionKey != keyShares.PublicKey || encrypted.KeyThreshold != 3 {
+		t.Errorf("election has key %q with threshold %d, want %q and 3", encrypted.EncryptionKey, encrypted.KeyThreshold, keyShares.PublicKey)
+	}
+	if _, err := elections.EnableEncryption(election.ID, keyShares.PublicKey, keyShares.Threshold); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("EnableEncryption() twice = %v, want ErrInvalid", err)
+	}
+
+	ballot := ballotOf(election.Candidates)
+	reversed := service.Ballot{ballot[2], ballot[1], ballot[0]}
+	for _, vote := range []struct {
+		email  string
+		ballot service.Ballot
+	}{
+		{"a@kth.se", ballot},
+		{"b@kth.se", ballot},
+		{"b@kth.se", reversed},
+		{"c@kth.se", reversed},
+	} {
+		if _, err := votes.Cast(election.ID, vote.email, "", vote.ballot, openTime.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Nothing in the database reveals the ballots before finalization
+	stored, err := repo.ListVotes(election.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, vote := range stored {
+		if len(vote.Rankings) != 0 {
+			t.Errorf("vote %s has rankings before finalization", vote.ID)
+		}
+	}
+	ciphertexts, err := repo.ListEncryptedBallots(election.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ciphertexts) != 3 {
+		t.Fatalf("got %d encrypted ballots, want 3", len(ciphertexts))
+	}
+	for _, e := range ciphertexts {
+		if bytes.Contains(e.Ciphertext, ballot[1].Bytes()) {
+			t.Errorf("encrypted ballot of vote %s contains a candidate id", e.VoteID)
+		}
+	}
+
+	other := createElection(t, elections, "Carol")
+	otherShares, err := service.GenerateElectionKey(3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := elections.EnableEncryption(other.ID, otherShares.PublicKey, otherShares.Threshold); err != nil {
+		t.Fatal(err)
+	}
+	for name, shares := range map[string][]string{
+		"no shares":        nil,
+		"too few shares":   keyShares.Shares[:2],
+		"duplicate shares": {keyShares.Shares[0], keyShares.Shares[0], keyShares.Shares[1]},
+		"malformed share":  {keyShares.Shares[0], keyShares.Shares[1], "not hex"},
+		"other election":   otherShares.Shares,
+	} {
+		if _, err := elections.Finalize(election.ID, shares); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("Finalize() with %s = %v, want ErrInvalid", name, err)
+		}
+	}
+	if _, err := votes.CountSchulze(election.ID, afterClose); !errors.Is(err, service.ErrNotFinalized) {
+		t.Errorf("CountSchulze() = %v, want ErrNotFinalized", err)
+	}
+
+	if _, err := elections.Finalize(election.ID, []string{keyShares.Shares[4], keyShares.Shares[1], keyShares.Shares[2]}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := votes.CountSchulze(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalVotes != 3 {
+		t.Errorf("counted %d votes, want 3", result.TotalVotes)
+	}
+	// b replaced their vote, so two of three ballots are reversed
+	if result.Ranking[0].ID != reversed[0] {
+		t.Errorf("winner = %s, want the first of the reversed ballot", result.Ranking[0].Name)
+	}
+	board, err := votes.BulletinBoard(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(board.Ballots) != 3 || len(board.Ballots[0].Ballot) != 3 {
+		t.Errorf("bulletin board = %+v, want three decrypted ballots", board.Ballots)
+	}
+}
+
+func TestEnableEncryptionInvalid(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice")
+
+	for _, amounts := range [][2]int{{3, 1}, {2, 3}, {256, 2}} {
+		if _, err := service.GenerateElectionKey(amounts[0], amounts[1]); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("GenerateElectionKey(%d shares, threshold %d) = %v, want ErrInvalid", amounts[0], amounts[1], err)
+		}
+	}
+	key, err := service.GenerateElectionKey(3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, params := range map[string]struct {
+		publicKey string
+		threshold int
+	}{
+		"no key":        {"", 2},
+		"malformed key": {"not base64", 2},
+		"short key":     {"AAAA", 2},
+		"threshold 1":   {key.PublicKey, 1},
+		"threshold 256": {key.PublicKey, 256},
+	} {
+		if _, err := elections.EnableEncryption(election.ID, params.publicKey, params.threshold); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("EnableEncryption() with %s = %v, want ErrInvalid", name, err)
+		}
+	}
+
+	if _, err := votes.Cast(election.ID, "a@kth.se", "", ballotOf(election.Candidates), openTime.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := elections.EnableEncryption(election.ID, key.PublicKey, key.Threshold); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("EnableEncryption() with votes = %v, want ErrInvalid", err)
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"durn/server/util"
+)
+
+// ErrInvalid matches all errors caused by invalid input, as opposed to
+// failures of the database. The messages of such errors are suitable as
+// responses to the client
+var ErrInvalid = errors.New("invalid input")
+
+type invalidError struct {
+	msg string
+}
+
+func (e *invalidError) Error() string {
+	return e.msg
+}
+
+func (e *invalidError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// invalid creates an error matching ErrInvalid with the formatted message
+func invalid(format string, args ...any) error {
+	return &invalidError{fmt.Sprintf(format, args...)}
+}
+
+var (
+	ErrInvalidElection   = invalid(util.InvalidElectionMessage)
+	ErrInvalidCandidate  = invalid("Invalid candidate specified")
+	ErrNotFinalized      = invalid("Can't count votes of unfinalized election")
+	ErrVotingClosed      = invalid("Voting is not open for the specified election")
+	ErrInvalidBallot     = invalid("Missing or invalid candidates in vote")
+	ErrBoardNotPublished = invalid("Ballots are published when the election is finalized")
+	ErrUnknownReceipt    = invalid("No ballot with the given receipt")
+	ErrResultsHidden     = invalid("Ballots and results are hidden until voting has closed")
+)
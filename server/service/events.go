@@ -0,0 +1,112 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	database "durn/server/db"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Types of events
+const (
+	EventElectionCreated     = "election-created"
+	EventElectionUpdated     = "election-updated"
+	EventElectionPublished   = "election-published"
+	EventElectionUnpublished = "election-unpublished"
+	EventElectionFinalized   = "election-finalized"
+	EventElectionDeleted     = "election-deleted"
+	EventVotingOpened        = "voting-opened"
+	EventVotingClosed        = "voting-closed"
+	EventTurnout             = "turnout"
+)
+
+// Event is a change to an election. Public events are sent to voters, and
+// must not contain anything voters are not allowed to see
+type Event struct {
+	Type     string    `json:"type"`
+	Election uuid.UUID `json:"election"`
+	Public   bool      `json:"-"`
+	Data     any       `json:"data,omitempty"`
+}
+
+// subscriberBuffer is how many events a subscriber can fall behind before
+// events to it are dropped
+const subscriberBuffer = 32
+
+// Events is an in-process publisher of events to any amount of subscribers
+type Events struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewEvents() *Events {
+	return &Events{subscribers: map[chan Event]struct{}{}}
+}
+
+// Subscribe returns a channel receiving all events published from now on,
+// and a function that ends the subscription and closes the channel
+func (e *Events) Subscribe() (<-chan Event, func()) {
+	events := make(chan Event, subscriberBuffer)
+	e.mu.Lock()
+	e.subscribers[events] = struct{}{}
+	e.mu.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			e.mu.Lock()
+			delete(e.subscribers, events)
+			e.mu.Unlock()
+			close(events)
+		})
+	}
+}
+
+// Publish sends the event to all subscribers. Never blocks, so subscribers
+// that are too slow to keep up miss events
+func (e *Events) Publish(event Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for events := range e.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// PublishVotingWindows publishes that voting has opened or closed for the
+// elections whose open or close time is after from and not after to
+func (e *Events) PublishVotingWindows(elections []database.Election, from time.Time, to time.Time) {
+	crossed := func(t time.Time) bool {
+		return t.After(from) && !t.After(to)
+	}
+	for _, election := range elections {
+		if election.OpenTime.Valid && crossed(election.OpenTime.Time) {
+			e.Publish(Event{Type: EventVotingOpened, Election: election.ID, Public: true})
+		}
+		if election.CloseTime.Valid && crossed(election.CloseTime.Time) {
+			e.Publish(Event{Type: EventVotingClosed, Election: election.ID, Public: true})
+		}
+	}
+}
+
+// WatchVotingWindows publishes when voting opens or closes, checking the
+// elections every interval. Never returns
+func (e *Events) WatchVotingWindows(elections *Elections, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := time.Now()
+	for now := range ticker.C {
+		list, err := elections.List()
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		e.PublishVotingWindows(list, last, now)
+		last = now
+	}
+}
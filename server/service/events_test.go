@@ -0,0 +1,84 @@
+package service_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/service"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+func TestEventsPublish(t *testing.T) {
+	events := service.NewEvents()
+	first, unsubscribeFirst := events.Subscribe()
+	second, unsubscribeSecond := events.Subscribe()
+	defer unsubscribeSecond()
+
+	event := service.Event{Type: service.EventElectionCreated, Election: uuid.NewV4()}
+	events.Publish(event)
+	for _, subscriber := range []<-chan service.Event{first, second} {
+		if got := <-subscriber; got.Type != event.Type || got.Election != event.Election {
+			t.Errorf("received %+v, want %+v", got, event)
+		}
+	}
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	events.Publish(event)
+	if _, ok := <-first; ok {
+		t.Error("unsubscribed channel received an event")
+	}
+	<-second
+}
+
+func TestEventsSlowSubscriber(t *testing.T) {
+	events := service.NewEvents()
+	_, unsubscribe := events.Subscribe()
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			events.Publish(service.Event{Type: service.EventTurnout})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a subscriber that does not receive")
+	}
+}
+
+func TestPublishVotingWindows(t *testing.T) {
+	events := service.NewEvents()
+	received, unsubscribe := events.Subscribe()
+	defer unsubscribe()
+
+	at := func(t time.Time) sql.NullTime { return sql.NullTime{Time: t, Valid: true} }
+	opening := database.Election{ID: uuid.NewV4(), OpenTime: at(openTime), CloseTime: at(closeTime)}
+	closing := database.Election{ID: uuid.NewV4(), OpenTime: at(openTime.Add(-time.Hour)), CloseTime: at(openTime)}
+	later := database.Election{ID: uuid.NewV4(), OpenTime: at(closeTime), CloseTime: at(closeTime.Add(time.Hour))}
+	noTimes := database.Election{ID: uuid.NewV4()}
+
+	events.PublishVotingWindows([]database.Election{opening, closing, later, noTimes}, openTime.Add(-time.Second), openTime)
+	unsubscribe()
+
+	var got []service.Event
+	for event := range received {
+		got = append(got, event)
+	}
+	if len(got) != 2 ||
+		got[0].Type != service.EventVotingOpened || got[0].Election != opening.ID ||
+		got[1].Type != service.EventVotingClosed || got[1].Election != closing.ID {
+		t.Errorf("events = %+v, want opened for the first election and closed for the second", got)
+	}
+	for _, event := range got {
+		if !event.Public {
+			t.Errorf("%s is not public", event.Type)
+		}
+	}
+}
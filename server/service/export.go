@@ -0,0 +1,178 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	database "durn/server/db"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	BallotExportFormat  = "durn-ballots"
+	BallotExportVersion = 1
+)
+
+// BallotExport contains the anonymised ballots of a finalized election
+// together with everything needed to count them again, independently of
+// the server. Ballots are shuffled and contain no timestamps or voters.
+// Ballots rank all candidates, also withdrawn ones, which have to be removed
+// with RemoveWithdrawn before counting. Weights has the weight of each ballot
+// in the same order, and is left out when every ballot has weight 1. Exports
+// from the server list a ballot once for each unit of its weight instead, so
+// that weights can't link ballots to voters, and have no weights
+type BallotExport struct {
+	Format     string              `json:"format"`
+	Version    int                 `json:"version"`
+	Election   ExportedElection    `json:"election"`
+	Candidates []ExportedCandidate `json:"candidates"`
+	Ballots    []Ballot            `json:"ballots"`
+	Weights    []int               `json:"weights,omitempty"`
+}
+
+type ExportedElection struct {
+	ID            uuid.UUID `json:"id"`
+	Name          string    `json:"name"`
+	Mandates      int       `json:"mandates"`
+	ExtraMandates int       `json:"extraMandates"`
+}
+
+// ExportedCandidate is a candidate in an export. Files exported before
+// symbolic candidates had kinds lack SymbolicKind, and their symbolic
+// candidates are read as vacant
+type ExportedCandidate struct {
+	ID           uuid.UUID             `json:"id"`
+	Name         string                `json:"name"`
+	Symbolic     bool                  `json:"symbolic"`
+	SymbolicKind database.SymbolicKind `json:"symbolicKind,omitempty"`
+	Withdrawn    bool                  `json:"withdrawn"`
+}
+
+// Export returns the anonymised ballots of a finalized election that has
+// closed
+func (s *Votes) Export(electionId uuid.UUID, now time.Time) (BallotExport, error) {
+	election, ballots, weights, err := s.countableElection(electionId, now)
+	if err != nil {
+		return BallotExport{}, err
+	}
+	ballots = unitBallots(ballots, weights)
+	rand.Shuffle(len(ballots), func(i, j int) {
+		ballots[i], ballots[j] = ballots[j], ballots[i]
+	})
+
+	return BallotExport{
+		Format:     BallotExportFormat,
+		Version:    BallotExportVersion,
+		Election:   exportedElection(election),
+		Candidates: exportedCandidates(election),
+		Ballots:    ballots,
+	}, nil
+}
+
+// unitBallots repeats each ballot as many times as its weight, which counts
+// the same as the weighted ballots
+func unitBallots(ballots []Ballot, weights []int) []Ballot {
+	units := make([]Ballot, 0, len(ballots))
+	for i, ballot := range ballots {
+		for n := 0; n < weightOf(weights, i); n++ {
+			units = append(units, ballot)
+		}
+	}
+	return units
+}
+
+func exportedElection(election database.Election) ExportedElection {
+	return ExportedElection{
+		ID:            election.ID,
+		Name:          election.Name,
+		Mandates:      election.Mandates,
+		ExtraMandates: election.ExtraMandates,
+	}
+}
+
+func exportedCandidates(election database.Election) []ExportedCandidate {
+	var candidates []ExportedCandidate
+	for _, candidate := range election.Candidates {
+		candidates = append(candidates, ExportedCandidate{
+			ID:           candidate.ID,
+			Name:         candidate.Name,
+			Symbolic:     candidate.Symbolic,
+			SymbolicKind: candidate.SymbolicKind,
+			Withdrawn:    candidate.Withdrawn,
+		})
+	}
+	return candidates
+}
+
+// ReadBallotExport reads an exported ballot file, validating that every
+// ballot ranks all candidates of the election. Also reads bulletin boards,
+// see ReadBulletinBoard
+func ReadBallotExport(r io.Reader) (BallotExport, error) {
+	var export BallotExport
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return export, err
+	}
+	var header struct {
+		Format string `json:"format"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return export, invalid("Malformed ballot file: %s", err)
+	}
+	if header.Format == BulletinBoardFormat {
+		board, err := ReadBulletinBoard(data)
+		if err != nil {
+			return export, err
+		}
+		export = board.BallotExport()
+	} else if err := json.Unmarshal(data, &export); err != nil {
+		return export, invalid("Malformed ballot file: %s", err)
+	}
+	if export.Format != BallotExportFormat {
+		return export, invalid("Not a ballot file, format is '%s'", export.Format)
+	}
+	if export.Version != BallotExportVersion {
+		return export, invalid("Unsupported ballot file version %d", export.Version)
+	}
+
+	for i, candidate := range export.Candidates {
+		if candidate.Symbolic && candidate.SymbolicKind == "" {
+			export.Candidates[i].SymbolicKind = database.SymbolicVacant
+		}
+	}
+
+	if export.Weights != nil && len(export.Weights) != len(export.Ballots) {
+		return export, invalid("There are %d weights for %d ballots", len(export.Weights), len(export.Ballots))
+	}
+	candidates := export.CandidateList()
+	for i, ballot := range export.Ballots {
+		if err := ValidateBallot(candidates, ballot); err != nil {
+			return export, fmt.Errorf("ballot %d: %w", i+1, err)
+		}
+		if weight := weightOf(export.Weights, i); weight < 1 {
+			return export, fmt.Errorf("ballot %d: %w", i+1, invalid("The weight %d is less than 1", weight))
+		}
+	}
+	return export, nil
+}
+
+// CandidateList returns the candidates of the export in the form used by
+// the counting functions
+func (e BallotExport) CandidateList() []database.Candidate {
+	var candidates []database.Candidate
+	for _, candidate := range e.Candidates {
+		candidates = append(candidates, database.Candidate{
+			ID:           candidate.ID,
+			Name:         candidate.Name,
+			ElectionID:   e.Election.ID,
+			Symbolic:     candidate.Symbolic,
+			SymbolicKind: candidate.SymbolicKind,
+			Withdrawn:    candidate.Withdrawn,
+		})
+	}
+	return candidates
+}
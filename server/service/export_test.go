@@ -0,0 +1,102 @@
+package service_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"durn/server/service"
+	"durn/server/service/servicetest"
+)
+
+func TestExportRecount(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob", "Carol")
+	now := openTime.Add(time.Hour)
+
+	ballot := ballotOf(election.Candidates)
+	for i, b := range []service.Ballot{
+		{ballot[1], ballot[2], ballot[3], ballot[0]},
+		{ballot[1], ballot[3], ballot[2], ballot[0]},
+		{ballot[2], ballot[1], ballot[3], ballot[0]},
+	} {
+		if _, err := votes.Cast(election.ID, string(rune('a'+i))+"@kth.se", "", b, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := votes.Export(election.ID, afterClose); !errors.Is(err, service.ErrNotFinalized) {
+		t.Errorf("Export() before finalizing = %v, want ErrNotFinalized", err)
+	}
+	if _, err := elections.Finalize(election.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	export, err := votes.Export(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file bytes.Buffer
+	if err := json.NewEncoder(&file).Encode(export); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(file.String(), "kth.se") || strings.Contains(file.String(), "2023") {
+		t.Errorf("export contains voters or timestamps: %s", file.String())
+	}
+
+	read, err := service.ReadBallotExport(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Ballots) != 3 {
+		t.Errorf("got %d ballots, want 3", len(read.Ballots))
+	}
+
+	official, err := votes.CountSchulze(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recount := service.Schulze(read.CandidateList(), read.Ballots, read.Weights)
+	if !reflect.DeepEqual(recount.VoteMatrix, official.VoteMatrix) || recount.Ranking[0].ID != official.Ranking[0].ID {
+		t.Errorf("recount = %+v, want %+v", recount, official)
+	}
+}
+
+func TestReadBallotExportInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"not json", `ballots`},
+		{"wrong format", `{"format": "other", "version": 1}`},
+		{"wrong version", `{"format": "durn-ballots", "version": 2}`},
+		{"invalid ballot", `{"format": "durn-ballots", "version": 1,
+			"candidates": [{"id": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, {"id": "6ba7b811-9dad-11d1-80b4-00c04fd430c8"}],
+			"ballots": [["6ba7b810-9dad-11d1-80b4-00c04fd430c8"]]}`},
+		{"missing weights", `{"format": "durn-ballots", "version": 1,
+			"candidates": [{"id": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}],
+			"ballots": [["6ba7b810-9dad-11d1-80b4-00c04fd430c8"], ["6ba7b810-9dad-11d1-80b4-00c04fd430c8"]],
+			"weights": [2]}`},
+		{"zero weight", `{"format": "durn-ballots", "version": 1,
+			"candidates": [{"id": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}],
+			"ballots": [["6ba7b810-9dad-11d1-80b4-00c04fd430c8"], ["6ba7b810-9dad-11d1-80b4-00c04fd430c8"]],
+			"weights": [2, 0]}`},
+		{"negative weight", `{"format": "durn-ballots", "version": 1,
+			"candidates": [{"id": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}],
+			"ballots": [["6ba7b810-9dad-11d1-80b4-00c04fd430c8"]],
+			"weights": [-1]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := service.ReadBallotExport(strings.NewReader(test.file)); !errors.Is(err, service.ErrInvalid) {
+				t.Errorf("ReadBallotExport() = %v, want an ErrInvalid error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// BallotFormat is a file format that exported ballots can be written in
+type BallotFormat struct {
+	Extension   string
+	ContentType string
+	Write       func(w io.Writer, export BallotExport) error
+}
+
+// BallotFormats are the supported formats for exported ballots, by name.
+// All formats except json aggregate identical ballots, summing their weights
+var BallotFormats = map[string]BallotFormat{
+	"json": {"json", "application/json; charset=utf-8", writeBallotsJSON},
+	"blt":  {"blt", "text/plain; charset=utf-8", writeBallotsBLT},
+	"soc": {"soc", "text/plain; charset=utf-8", func(w io.Writer, export BallotExport) error {
+		return writeBallotsPrefLib(w, export, true)
+	}},
+	"soi": {"soi", "text/plain; charset=utf-8", func(w io.Writer, export BallotExport) error {
+		return writeBallotsPrefLib(w, export, false)
+	}},
+	"csv": {"csv", "text/csv; charset=utf-8", writeBallotsCSV},
+}
+
+// BallotCount is a ballot together with the amount of votes with that ballot,
+// counting each vote as its weight
+type BallotCount struct {
+	Ballot Ballot
+	Count  int
+}
+
+// AggregateBallots groups identical ballots, ordered by the amount of votes.
+// Each ballot counts as its weight in weights, or as one without weights.
+// Ballots with the same amount of votes keep the order of the input
+func AggregateBallots(ballots []Ballot, weights []int) []BallotCount {
+	var counts []BallotCount
+	indexes := map[string]int{}
+	for n, ballot := range ballots {
+		var key strings.Builder
+		for _, candidate := range ballot {
+			key.WriteString(candidate.String())
+		}
+		if i, ok := indexes[key.String()]; ok {
+			counts[i].Count += weightOf(weights, n)
+			continue
+		}
+		indexes[key.String()] = len(counts)
+		counts = append(counts, BallotCount{Ballot: ballot, Count: weightOf(weights, n)})
+	}
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	return counts
+}
+
+func writeBallotsJSON(w io.Writer, export BallotExport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+// writeBallotsBLT writes the ballots in the BLT format used by OpenSTV and
+// other STV counting programs. Candidates are numbered from 1 in the order of
+// the export, and withdrawn candidates are listed as negative numbers on a
+// single line after the first line
+//
+// https://www.opavote.com/help/overview#blt-file-format
+func writeBallotsBLT(w io.Writer, export BallotExport) error {
+	numbers := export.candidateNumbers()
+	fmt.Fprintf(w, "%d %d\n", len(export.Candidates), export.Election.Mandates)
+	var withdrawn []string
+	for _, candidate := range export.Candidates {
+		if candidate.Withdrawn {
+			withdrawn = append(withdrawn, fmt.Sprintf("-%d", numbers[candidate.ID]))
+		}
+	}
+	if len(withdrawn) > 0 {
+		fmt.Fprintln(w, strings.Join(withdrawn, " "))
+	}
+	for _, ballot := range AggregateBallots(export.Ballots, export.Weights) {
+		fmt.Fprint(w, ballot.Count)
+		for _, candidate := range ballot.Ballot {
+			fmt.Fprintf(w, " %d", numbers[candidate])
+		}
+		fmt.Fprintln(w, " 0")
+	}
+	fmt.Fprintln(w, "0")
+	for _, candidate := range export.Candidates {
+		fmt.Fprintln(w, bltQuote(candidate.Name))
+	}
+	_, err := fmt.Fprintln(w, bltQuote(export.Election.Name))
+	return err
+}
+
+// BLT has no way to escape quotes in names
+func bltQuote(s string) string {
+	return `"` + strings.ReplaceAll(singleLine(s), `"`, `'`) + `"`
+}
+
+// writeBallotsPrefLib writes the ballots in the PrefLib format for strict
+// orders. Complete orders (soc) rank all candidates including symbolic and
+// withdrawn ones, while incomplete orders (soi) only rank the candidates that
+// are running
+//
+// https://www.preflib.org/format
+func writeBallotsPrefLib(w io.Writer, export BallotExport, complete bool) error {
+	dataType := "soc"
+	if !complete {
+		dataType = "soi"
+	}
+
+	numbers := map[uuid.UUID]int{}
+	var names []string
+	for _, candidate := range export.Candidates {
+		if complete || !(candidate.Symbolic || candidate.Withdrawn) {
+			names = append(names, candidate.Name)
+			numbers[candidate.ID] = len(names)
+		}
+	}
+
+	var ballots []Ballot
+	for _, ballot := range export.Ballots {
+		var ranked Ballot
+		for _, candidate := range ballot {
+			if _, ok := numbers[candidate]; ok {
+				ranked = append(ranked, candidate)
+			}
+		}
+		ballots = append(ballots, ranked)
+	}
+	aggregated := AggregateBallots(ballots, export.Weights)
+	voters := 0
+	for _, ballot := range aggregated {
+		voters += ballot.Count
+	}
+
+	fmt.Fprintf(w, "# FILE NAME: ballots-%s.%s\n", export.Election.ID, dataType)
+	fmt.Fprintf(w, "# TITLE: %s\n", singleLine(export.Election.Name))
+	fmt.Fprintf(w, "# DATA TYPE: %s\n", dataType)
+	fmt.Fprintln(w, "# MODIFICATION TYPE: original")
+	fmt.Fprintf(w, "# NUMBER ALTERNATIVES: %d\n", len(names))
+	fmt.Fprintf(w, "# NUMBER VOTERS: %d\n", voters)
+	fmt.Fprintf(w, "# NUMBER UNIQUE ORDERS: %d\n", len(aggregated))
+	for i, name := range names {
+		fmt.Fprintf(w, "# ALTERNATIVE NAME %d: %s\n", i+1, singleLine(name))
+	}
+	for _, ballot := range aggregated {
+		ranks := make([]string, len(ballot.Ballot))
+		for i, candidate := range ballot.Ballot {
+			ranks[i] = strconv.Itoa(numbers[candidate])
+		}
+		if _, err := fmt.Fprintf(w, "%d: %s\n", ballot.Count, strings.Join(ranks, ",")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBallotsCSV writes the ballots as CSV with one row per unique ballot,
+// containing the amount of votes followed by the names of the candidates in
+// ranked order
+func writeBallotsCSV(w io.Writer, export BallotExport) error {
+	names := map[uuid.UUID]string{}
+	for _, candidate := range export.Candidates {
+		names[candidate.ID] = candidate.Name
+	}
+
+	out := csv.NewWriter(w)
+	header := []string{"count"}
+	for i := range export.Candidates {
+		header = append(header, fmt.Sprintf("rank %d", i+1))
+	}
+	out.Write(header)
+	for _, ballot := range AggregateBallots(export.Ballots, export.Weights) {
+		row := []string{strconv.Itoa(ballot.Count)}
+		for _, candidate := range ballot.Ballot {
+			row = append(row, names[candidate])
+		}
+		out.Write(row)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// candidateNumbers numbers the candidates from 1 in the order of the export
+func (e BallotExport) candidateNumbers() map[uuid.UUID]int {
+	numbers := map[uuid.UUID]int{}
+	for i, candidate := range e.Candidates {
+		numbers[candidate.ID] = i + 1
+	}
+	return numbers
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
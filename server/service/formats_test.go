@@ -0,0 +1,151 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+func testExport() BallotExport {
+	a := uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c1")
+	b := uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c2")
+	v := uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c3")
+	return BallotExport{
+		Format:  BallotExportFormat,
+		Version: BallotExportVersion,
+		Election: ExportedElection{
+			ID:       uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c0"),
+			Name:     `Ordförande "2024"`,
+			Mandates: 1,
+		},
+		Candidates: []ExportedCandidate{
+			{ID: a, Name: "Alice"},
+			{ID: b, Name: "Bob, Jr."},
+			{ID: v, Name: "Vakant", Symbolic: true},
+		},
+		Ballots: []Ballot{
+			{b, a, v},
+			{a, b, v},
+			{a, v, b},
+			{a, b, v},
+		},
+	}
+}
+
+func TestAggregateBallots(t *testing.T) {
+	export := testExport()
+	counts := AggregateBallots(export.Ballots, nil)
+	if len(counts) != 3 {
+		t.Fatalf("got %d unique ballots, want 3", len(counts))
+	}
+	if counts[0].Count != 2 || counts[0].Ballot[0] != export.Candidates[0].ID {
+		t.Errorf("most common ballot = %+v, want a, b, v twice", counts[0])
+	}
+	if counts[1].Ballot[0] != export.Candidates[1].ID || counts[2].Ballot[1] != export.Candidates[2].ID {
+		t.Errorf("ballots with the same count did not keep their order: %+v", counts)
+	}
+}
+
+func TestBallotFormats(t *testing.T) {
+	tests := map[string]string{
+		"blt": `3 1
+2 1 2 3 0
+1 2 1 3 0
+1 1 3 2 0
+0
+"Alice"
+"Bob, Jr."
+"Vakant"
+"Ordförande '2024'"
+`,
+		"soc": `# FILE NAME: ballots-6ba7b810-9dad-11d1-80b4-00c04fd430c0.soc
+# TITLE: Ordförande "2024"
+# DATA TYPE: soc
+# MODIFICATION TYPE: original
+# NUMBER ALTERNATIVES: 3
+# NUMBER VOTERS: 4
+# NUMBER UNIQUE ORDERS: 3
+# ALTERNATIVE NAME 1: Alice
+# ALTERNATIVE NAME 2: Bob, Jr.
+# ALTERNATIVE NAME 3: Vakant
+2: 1,2,3
+1: 2,1,3
+1: 1,3,2
+`,
+		"soi": `# FILE NAME: ballots-6ba7b810-9dad-11d1-80b4-00c04fd430c0.soi
+# TITLE: Ordförande "2024"
+# DATA TYPE: soi
+# MODIFICATION TYPE: original
+# NUMBER ALTERNATIVES: 2
+# NUMBER VOTERS: 4
+# NUMBER UNIQUE ORDERS: 2
+# ALTERNATIVE NAME 1: Alice
+# ALTERNATIVE NAME 2: Bob, Jr.
+3: 1,2
+1: 2,1
+`,
+		"csv": `count,rank 1,rank 2,rank 3
+2,Alice,"Bob, Jr.",Vakant
+1,"Bob, Jr.",Alice,Vakant
+1,Alice,Vakant,"Bob, Jr."
+`,
+	}
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := BallotFormats[name].Write(&out, testExport()); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != want {
+				t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+			}
+		})
+	}
+}
+
+func TestBallotFormatsWithdrawn(t *testing.T) {
+	export := testExport()
+	export.Candidates[1].Withdrawn = true
+
+	var blt bytes.Buffer
+	if err := BallotFormats["blt"].Write(&blt, export); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(blt.Bytes(), []byte("3 1\n-2\n2 1 2 3 0\n")) {
+		t.Errorf("withdrawn candidate not listed in BLT:\n%s", blt.String())
+	}
+
+	// All withdrawn candidates are on the same line
+	export.Candidates[2].Withdrawn = true
+	blt.Reset()
+	if err := BallotFormats["blt"].Write(&blt, export); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(blt.Bytes(), []byte("3 1\n-2 -3\n2 1 2 3 0\n")) {
+		t.Errorf("withdrawn candidates not listed on one line in BLT:\n%s", blt.String())
+	}
+	export.Candidates[2].Withdrawn = false
+
+	var soi bytes.Buffer
+	if err := BallotFormats["soi"].Write(&soi, export); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(soi.Bytes(), []byte("# NUMBER UNIQUE ORDERS: 1\n# ALTERNATIVE NAME 1: Alice\n4: 1\n")) {
+		t.Errorf("withdrawn candidate included in soi:\n%s", soi.String())
+	}
+}
+
+func TestBallotFormatJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := BallotFormats["json"].Write(&out, testExport()); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadBallotExport(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Ballots) != 4 {
+		t.Errorf("got %d ballots, want 4", len(read.Ballots))
+	}
+}
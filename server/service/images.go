@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	database "durn/server/db"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// imageTypes are the allowed image types, detected from the content of the
+// file, and the extensions they are stored with. SVG is not allowed since it
+// can contain scripts
+var imageTypes = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// Images handles images on the profiles of candidates
+type Images struct {
+	repo    Repository
+	blobs   BlobStore
+	maxSize int64
+}
+
+func NewImages(repo Repository, blobs BlobStore, maxSize int64) *Images {
+	return &Images{repo: repo, blobs: blobs, maxSize: maxSize}
+}
+
+// MaxSize is the largest allowed image, in bytes
+func (s *Images) MaxSize() int64 {
+	return s.maxSize
+}
+
+// SetCandidateImage validates and stores an image, and sets it as the image
+// of the candidate, replacing any previous image
+func (s *Images) SetCandidateImage(candidateId uuid.UUID, data []byte) (database.Candidate, error) {
+	candidate, err := getCandidate(s.repo, candidateId)
+	if err != nil {
+		return candidate, err
+	}
+
+	if int64(len(data)) > s.maxSize {
+		return candidate, invalid("Image is larger than %d bytes", s.maxSize)
+	}
+	contentType := http.DetectContentType(data)
+	extension, ok := imageTypes[contentType]
+	if !ok {
+		return candidate, invalid("Unsupported image type %s, use png, jpeg, gif or webp", contentType)
+	}
+
+	name := fmt.Sprintf("%s.%s", uuid.NewV4(), extension)
+	if err := s.blobs.Save(name, data); err != nil {
+		return candidate, err
+	}
+	previous := candidate.Image
+	candidate.Image = name
+	if err := s.repo.SaveCandidate(&candidate); err != nil {
+		s.blobs.Delete(name)
+		return candidate, err
+	}
+	if previous != "" {
+		if err := s.blobs.Delete(previous); err != nil {
+			fmt.Println(err)
+		}
+	}
+	return candidate, nil
+}
+
+// RemoveCandidateImage removes the image of the candidate
+func (s *Images) RemoveCandidateImage(candidateId uuid.UUID) (database.Candidate, error) {
+	candidate, err := getCandidate(s.repo, candidateId)
+	if err != nil {
+		return candidate, err
+	}
+	if candidate.Image == "" {
+		return candidate, nil
+	}
+
+	previous := candidate.Image
+	candidate.Image = ""
+	if err := s.repo.SaveCandidate(&candidate); err != nil {
+		return candidate, err
+	}
+	if err := s.blobs.Delete(previous); err != nil {
+		fmt.Println(err)
+	}
+	return candidate, nil
+}
+
+// Open opens a stored image, returning ErrNotFound if there is none
+func (s *Images) Open(name string) (io.ReadSeekCloser, time.Time, error) {
+	return s.blobs.Open(name)
+}
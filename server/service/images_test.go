@@ -0,0 +1,152 @@
+package service_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/service"
+	"durn/server/service/servicetest"
+)
+
+// pngHeader is enough of a png file for the type to be detected
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newImages(t *testing.T, maxSize int64) (*service.Elections, *service.Images, *service.DirBlobStore) {
+	t.Helper()
+	repo := servicetest.NewRepository(t)
+	blobs, err := service.NewDirBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service.NewElections(repo), service.NewImages(repo, blobs, maxSize), blobs
+}
+
+func TestSetCandidateImage(t *testing.T) {
+	elections, images, blobs := newImages(t, 1024)
+	election := createElection(t, elections, "Alice")
+	alice := election.Candidates[0]
+
+	first, err := images.SetCandidateImage(alice.ID, pngHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(first.Image, ".png") {
+		t.Errorf("image = %q, want a png", first.Image)
+	}
+	f, _, err := blobs.Open(first.Image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != string(pngHeader) {
+		t.Errorf("stored %q, want %q", data, pngHeader)
+	}
+
+	second, err := images.SetCandidateImage(alice.ID, pngHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Image == first.Image {
+		t.Error("replaced image kept the same name")
+	}
+	if _, _, err := blobs.Open(first.Image); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("opening replaced image returned %v, want ErrNotFound", err)
+	}
+
+	removed, err := images.RemoveCandidateImage(alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed.Image != "" {
+		t.Errorf("image = %q after removing", removed.Image)
+	}
+	if _, _, err := blobs.Open(second.Image); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("opening removed image returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestSetCandidateImageInvalid(t *testing.T) {
+	elections, images, _ := newImages(t, 32)
+	election := createElection(t, elections, "Alice")
+	alice := election.Candidates[0]
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)},
+		{"html", []byte("<html><script>alert(1)</script></html>")},
+		{"too large", append(append([]byte{}, pngHeader...), make([]byte, 32)...)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := images.SetCandidateImage(alice.ID, test.data); !errors.Is(err, service.ErrInvalid) {
+				t.Errorf("returned %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestDirBlobStoreNames(t *testing.T) {
+	blobs, err := service.NewDirBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"../.env", "/etc/passwd", "image.png", ""} {
+		if err := blobs.Save(name, pngHeader); err == nil {
+			t.Errorf("saving %q succeeded", name)
+		}
+		if _, _, err := blobs.Open(name); !errors.Is(err, service.ErrNotFound) {
+			t.Errorf("opening %q returned %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestCandidateLinks(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	election := createElection(t, elections)
+
+	links := database.Links{{Title: "Nominering", URL: "https://example.com/nominering"}}
+	candidate, err := elections.AddCandidate(election.ID, service.CandidateParams{
+		Name:         "Alice",
+		Presentation: "Hej **alla**",
+		Links:        links,
+	}, openTime.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	election, err = elections.Get(election.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range election.Candidates {
+		if c.ID != candidate.ID {
+			continue
+		}
+		if len(c.Links) != 1 || c.Links[0] != links[0] {
+			t.Errorf("links = %v, want %v", c.Links, links)
+		}
+		if !strings.Contains(c.PresentationHTML, "<strong>alla</strong>") {
+			t.Errorf("presentationHtml = %q, want rendered markdown", c.PresentationHTML)
+		}
+	}
+
+	invalidLinks := []database.Links{
+		{{Title: "", URL: "https://example.com"}},
+		{{Title: "Script", URL: "javascript:alert(1)"}},
+		{{Title: "Relative", URL: "/nominering"}},
+		make(database.Links, 11),
+	}
+	for _, links := range invalidLinks {
+		if _, err := elections.EditCandidate(candidate.ID, service.CandidateChanges{Links: &links}); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("editing links to %v returned %v, want ErrInvalid", links, err)
+		}
+	}
+}
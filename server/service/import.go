@@ -0,0 +1,171 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	database "durn/server/db"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// ImportDocument describes elections and their candidates to create at once
+type ImportDocument struct {
+	Elections []ImportedElection `json:"elections"`
+}
+
+// ImportedElection is an election in an import document. Omitted fields get
+// the same defaults as when creating an election
+type ImportedElection struct {
+	ElectionParams
+	Candidates []CandidateParams `json:"candidates"`
+}
+
+func (e *ImportedElection) UnmarshalJSON(data []byte) error {
+	type plain ImportedElection
+	value := plain{ElectionParams: DefaultElectionParams()}
+	if err := decodeStrict(data, &value); err != nil {
+		return err
+	}
+	*e = ImportedElection(value)
+	return nil
+}
+
+// ImportError is a problem with a single item of an import document. Item is
+// the path to the item, e.g. elections[0].candidates[2]
+type ImportError struct {
+	Item  string `json:"item"`
+	Error string `json:"error"`
+}
+
+// ImportErrors are all problems found in an import document
+type ImportErrors []ImportError
+
+func (e ImportErrors) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", err.Item, err.Error))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e ImportErrors) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// ParseImport parses a JSON import document. Unknown fields are rejected so
+// that misspelled fields are not silently ignored
+func ParseImport(data []byte) (ImportDocument, error) {
+	var doc ImportDocument
+	if err := decodeStrict(data, &doc); err != nil {
+		return doc, invalid("Malformed import document: %s", err)
+	}
+	return doc, nil
+}
+
+func decodeStrict(data []byte, value any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(value)
+}
+
+// Import creates all elections and candidates of the document. The whole
+// document is validated first, and if any item is invalid nothing is created
+// and all problems are returned as ImportErrors
+func (s *Elections) Import(doc ImportDocument) ([]database.Election, error) {
+	if errs := validateImport(doc); len(errs) > 0 {
+		return nil, errs
+	}
+
+	var elections []database.Election
+	for _, imported := range doc.Elections {
+		election := newElection(imported.ElectionParams)
+		for j, params := range imported.Candidates {
+			position := params.Position
+			if position == 0 {
+				position = j + 1
+			}
+			election.Candidates = append(election.Candidates, database.Candidate{
+				ID:           uuid.NewV4(),
+				Name:         params.Name,
+				Presentation: params.Presentation,
+				ElectionID:   election.ID,
+				Symbolic:     false,
+				Position:     position,
+				Links:        params.Links,
+				Translations: params.Translations,
+			})
+		}
+		elections = append(elections, election)
+	}
+
+	err := s.repo.Transaction(func(repo Repository) error {
+		for i := range elections {
+			if err := repo.CreateElection(&elections[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return elections, nil
+}
+
+func validateImport(doc ImportDocument) ImportErrors {
+	var errs ImportErrors
+	report := func(item string, format string, args ...any) {
+		errs = append(errs, ImportError{Item: item, Error: fmt.Sprintf(format, args...)})
+	}
+
+	if len(doc.Elections) == 0 {
+		report("elections", "No elections in document")
+	}
+	for i, election := range doc.Elections {
+		item := fmt.Sprintf("elections[%d]", i)
+		if strings.TrimSpace(election.Name) == "" {
+			report(item, "Missing name")
+		}
+		if election.Mandates < 1 {
+			report(item, "Mandates must be at least 1")
+		}
+		if election.ExtraMandates < 0 {
+			report(item, "Extra mandates can't be negative")
+		}
+		if election.OpenTime.Valid && election.CloseTime.Valid && !election.OpenTime.Time.Before(election.CloseTime.Time) {
+			report(item, "Open time must be before close time")
+		}
+		if err := validateElectionTranslations(election.Translations); err != nil {
+			report(item, "%s", err)
+		}
+		if err := validateSymbolicKinds(election.SymbolicCandidates); err != nil {
+			report(item, "%s", err)
+		}
+		if err := validateValidityRules(election.ValidityRules); err != nil {
+			report(item, "%s", err)
+		}
+
+		names := map[string]bool{}
+		for j, candidate := range election.Candidates {
+			item := fmt.Sprintf("elections[%d].candidates[%d]", i, j)
+			if strings.TrimSpace(candidate.Name) == "" {
+				report(item, "Missing name")
+			} else if err := validateCandidateName(candidate.Name); err != nil {
+				report(item, "%s", err)
+			} else if names[candidate.Name] {
+				report(item, "Duplicate candidate '%s'", candidate.Name)
+			}
+			if err := validateLinks(candidate.Links); err != nil {
+				report(item, "%s", err)
+			}
+			if err := validateCandidateTranslations(candidate.Translations); err != nil {
+				report(item, "%s", err)
+			}
+			names[candidate.Name] = true
+		}
+	}
+	return errs
+}
@@ -0,0 +1,131 @@
+package service_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"durn/server/service"
+	"durn/server/service/servicetest"
+	"durn/server/util"
+)
+
+const importYaml = `
+elections:
+  - name: Ordförande
+    description: Leder styrelsen
+    openTime: 2024-05-01T12:00:00+02:00
+    closeTime: 2024-05-01T14:00:00+02:00
+    candidates:
+      - name: Alice
+        presentation: Vill leda
+      - name: Bob
+  - name: Ledamöter
+    mandates: 3
+    extraMandates: 1
+`
+
+func parseYaml(t *testing.T, document string) service.ImportDocument {
+	t.Helper()
+	data, err := util.YamlToJson([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := service.ParseImport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestImport(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+
+	created, err := elections.Import(parseYaml(t, importYaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2 {
+		t.Fatalf("created %d elections, want 2", len(created))
+	}
+
+	first, err := elections.Get(created[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opens := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	if first.Name != "Ordförande" || first.Mandates != 1 || !first.OpenTime.Valid || !first.OpenTime.Time.Equal(opens) {
+		t.Errorf("first election = %+v", first)
+	}
+	var names []string
+	for _, candidate := range first.Candidates {
+		names = append(names, candidate.Name)
+		if candidate.Name == "Alice" && candidate.Presentation != "Vill leda" {
+			t.Errorf("presentation = %q, want %q", candidate.Presentation, "Vill leda")
+		}
+	}
+	if !util.SameSet(names, []string{"Alice", "Bob", util.VacantCandidate}) {
+		t.Errorf("candidates = %v, want Alice, Bob and %s", names, util.VacantCandidate)
+	}
+
+	second, err := elections.Get(created[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Mandates != 3 || second.ExtraMandates != 1 || second.OpenTime.Valid || len(second.Candidates) != 1 {
+		t.Errorf("second election = %+v", second)
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+
+	doc := parseYaml(t, `
+elections:
+  - name: Ordförande
+    candidates:
+      - name: Alice
+      - name: Vakant
+      - name: Alice
+  - name: ""
+    mandates: 0
+    openTime: 2024-05-01T14:00:00+02:00
+    closeTime: 2024-05-01T12:00:00+02:00
+`)
+	_, err := elections.Import(doc)
+	if !errors.Is(err, service.ErrInvalid) {
+		t.Fatalf("Import() = %v, want an ErrInvalid error", err)
+	}
+	var importErrors service.ImportErrors
+	if !errors.As(err, &importErrors) {
+		t.Fatalf("Import() = %v, want ImportErrors", err)
+	}
+	var items []string
+	for _, e := range importErrors {
+		items = append(items, e.Item)
+	}
+	want := []string{
+		"elections[0].candidates[1]",
+		"elections[0].candidates[2]",
+		"elections[1]",
+		"elections[1]",
+		"elections[1]",
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("errors = %v, want errors for %v", importErrors, want)
+	}
+
+	if list, _ := elections.List(); len(list) != 0 {
+		t.Errorf("%d elections were created from an invalid document", len(list))
+	}
+}
+
+func TestParseImportUnknownField(t *testing.T) {
+	_, err := service.ParseImport([]byte(`{"elections": [{"name": "Kassör", "mandate": 2}]}`))
+	if !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("ParseImport() = %v, want an ErrInvalid error", err)
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"strings"
+
+	database "durn/server/db"
+	"durn/server/util"
+)
+
+// validateTranslations checks that all translations are for supported
+// locales other than the default, which is stored on the election or
+// candidate itself
+func validateTranslations(translations database.Translations) error {
+	for locale := range translations {
+		if locale == util.DefaultLocale {
+			return invalid("Translations can't be for the default locale '%s'", locale)
+		}
+		if !util.IsLocale(locale) {
+			return invalid("Unsupported locale '%s', supported locales are %s", locale, strings.Join(util.Locales, ", "))
+		}
+	}
+	return nil
+}
+
+// validateElectionTranslations checks that the translations only contain the
+// name and description
+func validateElectionTranslations(translations database.Translations) error {
+	for locale, translation := range translations {
+		if translation.Presentation != "" {
+			return invalid("Elections have no presentation to translate, in locale '%s'", locale)
+		}
+	}
+	return validateTranslations(translations)
+}
+
+// validateCandidateTranslations checks that the translations only contain
+// the presentation, since names of candidates are not translated
+func validateCandidateTranslations(translations database.Translations) error {
+	for locale, translation := range translations {
+		if translation.Name != "" || translation.Description != "" {
+			return invalid("Only the presentation of candidates can be translated, in locale '%s'", locale)
+		}
+	}
+	return validateTranslations(translations)
+}
+
+// Localize returns the election with its name, description and the
+// presentations of its candidates in the given locale, falling back to the
+// default locale for missing translations. Symbolic candidates get the
+// name of their kind in the locale. Only meant for showing the election
+func Localize(election database.Election, locale string) database.Election {
+	if translation, ok := election.Translations[locale]; ok {
+		if translation.Name != "" {
+			election.Name = translation.Name
+		}
+		if translation.Description != "" {
+			election.Description = translation.Description
+		}
+	}
+
+	candidates := make([]database.Candidate, len(election.Candidates))
+	for i, candidate := range election.Candidates {
+		if translation, ok := candidate.Translations[locale]; ok && translation.Presentation != "" {
+			candidate.Presentation = translation.Presentation
+			candidate.PresentationHTML = util.RenderMarkdown(translation.Presentation)
+		}
+		if candidate.Symbolic && candidate.SymbolicKind.Valid() {
+			candidate.Name = candidate.SymbolicKind.Name(locale)
+		}
+		candidates[i] = candidate
+	}
+	election.Candidates = candidates
+	return election
+}
@@ -0,0 +1,89 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/service"
+	"durn/server/service/servicetest"
+	"durn/server/util"
+)
+
+func TestLocalize(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+
+	params := service.DefaultElectionParams()
+	params.Name = "Ordförande"
+	params.Description = "Leder styrelsen"
+	params.Translations = database.Translations{"en": {Name: "Chair"}}
+	election, err := elections.Create(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := elections.AddCandidate(election.ID, service.CandidateParams{
+		Name:         "Alice",
+		Presentation: "Jag vill **leda**",
+		Translations: database.Translations{"en": {Presentation: "I want to **lead**"}},
+	}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	election, err = elections.Get(election.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	english := service.Localize(election, "en")
+	if english.Name != "Chair" || english.Description != "Leder styrelsen" {
+		t.Errorf("english election = %q %q, want the name translated and the description in swedish", english.Name, english.Description)
+	}
+	names := map[string]database.Candidate{}
+	for _, candidate := range english.Candidates {
+		names[candidate.Name] = candidate
+	}
+	alice, ok := names["Alice"]
+	if !ok || alice.Presentation != "I want to **lead**" || !strings.Contains(alice.PresentationHTML, "<strong>lead</strong>") {
+		t.Errorf("english Alice = %+v, want the translated presentation", alice)
+	}
+	if _, ok := names["Vacant"]; !ok {
+		t.Errorf("english candidates = %v, want Vacant", names)
+	}
+
+	swedish := service.Localize(election, util.DefaultLocale)
+	if swedish.Name != "Ordförande" || swedish.Candidates[0].Presentation != "Jag vill **leda**" {
+		t.Errorf("swedish election = %+v, want the stored content", swedish)
+	}
+	if election.Candidates[len(election.Candidates)-1].Name != util.VacantCandidate {
+		t.Error("Localize changed the candidates of the original election")
+	}
+}
+
+func TestInvalidTranslations(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	election := createElection(t, elections, "Alice")
+
+	electionTranslations := []database.Translations{
+		{"de": {Name: "Vorsitz"}},
+		{"sv": {Name: "Ordförande"}},
+		{"en": {Presentation: "Hello"}},
+	}
+	for _, translations := range electionTranslations {
+		if _, err := elections.Edit(election.ID, service.ElectionChanges{Translations: &translations}); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("editing election translations to %v returned %v, want ErrInvalid", translations, err)
+		}
+	}
+
+	candidateTranslations := []database.Translations{
+		{"de": {Presentation: "Hallo"}},
+		{"en": {Name: "Alicia"}},
+	}
+	for _, translations := range candidateTranslations {
+		if _, err := elections.EditCandidate(election.Candidates[0].ID, service.CandidateChanges{Translations: &translations}); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("editing candidate translations to %v returned %v, want ErrInvalid", translations, err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMailMessage(t *testing.T) {
+	mail := Mail{To: "a@kth.se", Subject: "Röstningen\r\nBcc: b@kth.se", Body: "Hej!\n\n/dUrn"}
+	message, err := mail.message("dUrn <durn@kth.se>", time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, body, _ := strings.Cut(string(message), "\r\n\r\n")
+	if strings.Contains(header, "\r\nBcc:") {
+		t.Errorf("subject injected a header:\n%s", header)
+	}
+	if !strings.Contains(header, "Subject: =?utf-8?q?") {
+		t.Errorf("subject is not encoded:\n%s", header)
+	}
+	if body != "Hej!\r\n\r\n/dUrn" {
+		t.Errorf("body = %q", body)
+	}
+
+	mail.To = "a@kth.se\r\nBcc: b@kth.se"
+	if _, err := mail.message("dUrn <durn@kth.se>", time.Now()); err == nil {
+		t.Error("recipient with a line break was accepted")
+	}
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/util"
+)
+
+// Kinds of notifications
+const (
+	NotificationElectionOpen = "election-open"
+	NotificationReminder     = "reminder"
+	NotificationVoteRecorded = "vote-recorded"
+)
+
+// notificationQueueSize is how many notifications can wait to be sent before
+// new ones are dropped
+const notificationQueueSize = 1000
+
+// templates are the notification templates, templates/<locale>/<kind>.txt.
+// The first line of a template is the subject, and the rest the body
+//
+//go:embed templates
+var templateFS embed.FS
+
+var templates = loadTemplates()
+
+func loadTemplates() map[string]*template.Template {
+	result := map[string]*template.Template{}
+	for _, locale := range util.Locales {
+		for _, kind := range []string{NotificationElectionOpen, NotificationReminder, NotificationVoteRecorded} {
+			name := fmt.Sprintf("templates/%s/%s.txt", locale, kind)
+			result[name] = template.Must(template.ParseFS(templateFS, name))
+		}
+	}
+	return result
+}
+
+// NotificationOptions configure when and how fast notifications are sent
+type NotificationOptions struct {
+	// PublicURL is the url of the site, used for links to elections
+	PublicURL string
+	// Interval is the shortest time between two mails
+	Interval time.Duration
+	// ReminderBefore is how long before an election closes voters that
+	// have not voted are reminded
+	ReminderBefore time.Duration
+}
+
+// Notifications sends mails to voters when elections open, before they
+// close and when a vote is recorded. All mails are sent by Run, one at a time
+// with at least Interval between them
+type Notifications struct {
+	repo     Repository
+	mailer   Mailer
+	options  NotificationOptions
+	queue    chan Mail
+	lastSent time.Time
+}
+
+// NewNotifications creates notifications sent by the mailer. Notifications
+// are disabled if the mailer is nil
+func NewNotifications(repo Repository, mailer Mailer, options NotificationOptions) *Notifications {
+	return &Notifications{
+		repo:    repo,
+		mailer:  mailer,
+		options: options,
+		queue:   make(chan Mail, notificationQueueSize),
+	}
+}
+
+// notificationData is what the templates can use
+type notificationData struct {
+	Election  string
+	URL       string
+	CloseTime string
+	Time      string
+	Receipt   Receipt
+}
+
+func formatNotificationTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// render renders the notification in the locale of the voter, defaulting to
+// util.DefaultLocale
+func (s *Notifications) render(kind string, email string, locale string, election database.Election, receipt Receipt) (Mail, error) {
+	if !util.IsLocale(locale) {
+		locale = util.DefaultLocale
+	}
+	data := notificationData{
+		Election: Localize(election, locale).Name,
+		URL:      fmt.Sprintf("%s/vote/%s", strings.TrimSuffix(s.options.PublicURL, "/"), election.ID),
+		Time:     formatNotificationTime(receipt.Time),
+		Receipt:  receipt,
+	}
+	if election.CloseTime.Valid {
+		data.CloseTime = formatNotificationTime(election.CloseTime.Time)
+	}
+
+	var out bytes.Buffer
+	if err := templates[fmt.Sprintf("templates/%s/%s.txt", locale, kind)].Execute(&out, data); err != nil {
+		return Mail{}, err
+	}
+	subject, body, _ := strings.Cut(out.String(), "\n")
+	return Mail{To: email, Subject: subject, Body: strings.TrimLeft(body, "\n")}, nil
+}
+
+// send sends a mail, first waiting until Interval has passed since the last
+func (s *Notifications) send(mail Mail) error {
+	if wait := time.Until(s.lastSent.Add(s.options.Interval)); wait > 0 {
+		time.Sleep(wait)
+	}
+	s.lastSent = time.Now()
+	return s.mailer.Send(mail)
+}
+
+// VoteRecorded queues a notification to the voter that their vote was
+// recorded, with the receipt. The notification is dropped if the queue is full
+func (s *Notifications) VoteRecorded(email string, locale string, receipt Receipt) {
+	if s.mailer == nil {
+		return
+	}
+	election, err := getElection(s.repo, receipt.Election)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	mail, err := s.render(NotificationVoteRecorded, email, locale, election, receipt)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	select {
+	case s.queue <- mail:
+	default:
+		fmt.Println("Notification queue is full, dropping notification to", email)
+	}
+}
+
+// SendDue sends the notifications that are due at the given time. For every
+// open election, all voters that have not voted are told that it is open, and
+// reminded ReminderBefore it closes. Reminders are only sent after half of the
+// voting time, so that voters in short elections do not get two mails at once.
+// Each notification is only sent once to each voter. Mails that fail are
+// logged and sent again the next time, while the other voters still get theirs
+func (s *Notifications) SendDue(now time.Time) error {
+	if s.mailer == nil {
+		return nil
+	}
+	elections, err := s.repo.ListElections()
+	if err != nil {
+		return err
+	}
+	voters, err := s.repo.ListVoters()
+	if err != nil {
+		return err
+	}
+
+	for _, election := range elections {
+		if election.Finalized || !util.TimeIsInValidInterval(now, election.OpenTime, election.CloseTime) {
+			continue
+		}
+		if err := s.sendToNonVoters(NotificationElectionOpen, election, voters, now); err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		open, close := election.OpenTime.Time, election.CloseTime.Time
+		halfway := open.Add(close.Sub(open) / 2)
+		if s.options.ReminderBefore > 0 && !now.Before(close.Add(-s.options.ReminderBefore)) && !now.Before(halfway) {
+			if err := s.sendToNonVoters(NotificationReminder, election, voters, now); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+	return nil
+}
+
+// sendToNonVoters sends the notification to the voters that have neither
+// voted in the election nor already got the notification
+func (s *Notifications) sendToNonVoters(kind string, election database.Election, voters []database.ValidVoter, now time.Time) error {
+	skip := map[string]bool{}
+	castedVotes, err := s.repo.ListCastedVotes(election.ID)
+	if err != nil {
+		return err
+	}
+	for _, castedVote := range castedVotes {
+		skip[castedVote.Email] = true
+	}
+	sent, err := s.repo.ListSentNotifications(election.ID, kind)
+	if err != nil {
+		return err
+	}
+	for _, notification := range sent {
+		skip[notification.Email] = true
+	}
+
+	for _, voter := range voters {
+		if skip[voter.Email] {
+			continue
+		}
+		// A failed mail is retried the next time notifications are due,
+		// without holding back the mails to the other voters
+		mail, err := s.render(kind, voter.Email, voter.Locale, election, Receipt{})
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if err := s.send(mail); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if err := s.repo.CreateSentNotification(&database.SentNotification{
+			Email:      voter.Email,
+			ElectionID: election.ID,
+			Kind:       kind,
+			SentAt:     now,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run sends queued notifications, and the notifications that are due every
+// checkInterval. Returns immediately if notifications are disabled, and
+// otherwise never
+func (s *Notifications) Run(checkInterval time.Duration) {
+	if s.mailer == nil {
+		return
+	}
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case mail := <-s.queue:
+			if err := s.send(mail); err != nil {
+				fmt.Println(err)
+			}
+		case now := <-ticker.C:
+			if err := s.SendDue(now); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+}
+
+// SendQueued sends all queued notifications now, e.g. before shutting down
+func (s *Notifications) SendQueued() error {
+	for {
+		select {
+		case mail := <-s.queue:
+			if err := s.send(mail); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"durn/server/service"
+	"durn/server/service/servicetest"
+)
+
+// recordingMailer records the mails it is asked to send, and fails to send
+// mails to the failing recipients
+type recordingMailer struct {
+	mu      sync.Mutex
+	mails   []service.Mail
+	failing map[string]bool
+}
+
+func (m *recordingMailer) Send(mail service.Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failing[mail.To] {
+		return errors.New("mailbox unavailable")
+	}
+	m.mails = append(m.mails, mail)
+	return nil
+}
+
+// take returns the recorded mails and forgets them
+func (m *recordingMailer) take() []service.Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mails := m.mails
+	m.mails = nil
+	return mails
+}
+
+func recipients(mails []service.Mail) []string {
+	var result []string
+	for _, mail := range mails {
+		result = append(result, mail.To)
+	}
+	return result
+}
+
+func TestSendDue(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	voters := service.NewVoters(repo)
+	votes := service.NewVotes(repo)
+	mailer := &recordingMailer{}
+	notifications := service.NewNotifications(repo, mailer, service.NotificationOptions{
+		PublicURL:      "https://durn.example.com/",
+		ReminderBefore: 2 * time.Hour,
+	})
+
+	election := createElection(t, elections, "Alice", "Bob")
+	if err := voters.Add([]string{"a@kth.se", "b@kth.se"}, "sv", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := voters.Add([]string{"c@kth.se"}, "en", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := votes.Cast(election.ID, "b@kth.se", "", ballotOf(election.Candidates), openTime.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := notifications.SendDue(openTime.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if mails := mailer.take(); len(mails) != 0 {
+		t.Errorf("sent %v before the election opened", recipients(mails))
+	}
+
+	if err := notifications.SendDue(openTime.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	mails := mailer.take()
+	if got := strings.Join(recipients(mails), ","); got != "a@kth.se,c@kth.se" {
+		t.Fatalf("election open sent to %s, want voters that have not voted", got)
+	}
+	if want := "Röstningen i Ordförande har öppnat"; mails[0].Subject != want {
+		t.Errorf("subject = %q, want %q", mails[0].Subject, want)
+	}
+	if want := "Voting in Ordförande is open"; mails[1].Subject != want {
+		t.Errorf("subject = %q, want %q", mails[1].Subject, want)
+	}
+	if want := "https://durn.example.com/vote/" + election.ID.String(); !strings.Contains(mails[1].Body, want) {
+		t.Errorf("body does not link to %s:\n%s", want, mails[1].Body)
+	}
+
+	// Reminders are sent ReminderBefore close, and only once
+	if err := notifications.SendDue(closeTime.Add(-3 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if mails := mailer.take(); len(mails) != 0 {
+		t.Errorf("sent %v again before the reminder", recipients(mails))
+	}
+	if _, err := votes.Cast(election.ID, "a@kth.se", "", ballotOf(election.Candidates), closeTime.Add(-3*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := notifications.SendDue(closeTime.Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mails = mailer.take()
+	if got := strings.Join(recipients(mails), ","); got != "c@kth.se" {
+		t.Fatalf("reminder sent to %s, want c@kth.se once", got)
+	}
+	if !strings.HasPrefix(mails[0].Subject, "Reminder") {
+		t.Errorf("subject = %q, want a reminder", mails[0].Subject)
+	}
+
+	if err := notifications.SendDue(closeTime.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if mails := mailer.take(); len(mails) != 0 {
+		t.Errorf("sent %v after the election closed", recipients(mails))
+	}
+}
+
+func TestReminderAfterHalfway(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	voters := service.NewVoters(repo)
+	mailer := &recordingMailer{}
+	notifications := service.NewNotifications(repo, mailer, service.NotificationOptions{
+		ReminderBefore: 24 * time.Hour,
+	})
+	createElection(t, elections, "Alice")
+	if err := voters.Add([]string{"a@kth.se"}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The election is shorter than ReminderBefore, so the reminder waits
+	// until half of the voting time has passed
+	if err := notifications.SendDue(openTime); err != nil {
+		t.Fatal(err)
+	}
+	if mails := mailer.take(); len(mails) != 1 {
+		t.Fatalf("sent %d mails when the election opened, want 1", len(mails))
+	}
+	if err := notifications.SendDue(openTime.Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if mails := mailer.take(); len(mails) != 0 {
+		t.Errorf("sent %d mails before halfway, want 0", len(mails))
+	}
+	if err := notifications.SendDue(openTime.Add(3 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if mails := mailer.take(); len(mails) != 1 {
+		t.Errorf("sent %d reminders at halfway, want 1", len(mails))
+	}
+}
+
+func TestSendDueFailedMail(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	voters := service.NewVoters(repo)
+	mailer := &recordingMailer{failing: map[string]bool{"a@kth.se": true}}
+	notifications := service.NewNotifications(repo, mailer, service.NotificationOptions{})
+	createElection(t, elections, "Alice")
+	if err := voters.Add([]string{"a@kth.se", "b@kth.se"}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed mail does not stop the others, and is sent again next time
+	if err := notifications.SendDue(openTime); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(recipients(mailer.take()), ","); got != "b@kth.se" {
+		t.Fatalf("sent to %s, want b@kth.se", got)
+	}
+	mailer.failing = nil
+	if err := notifications.SendDue(openTime.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(recipients(mailer.take()), ","); got != "a@kth.se" {
+		t.Fatalf("sent to %s, want only the failed a@kth.se", got)
+	}
+}
+
+func TestVoteRecorded(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	mailer := &recordingMailer{}
+	notifications := service.NewNotifications(repo, mailer, service.NotificationOptions{})
+	election := createElection(t, elections, "Alice")
+
+	receipt := service.NewReceipt(election.ID, "a@kth.se", "secret", ballotOf(election.Candidates), openTime)
+	notifications.VoteRecorded("a@kth.se", "en", receipt)
+	if mails := mailer.take(); len(mails) != 0 {
+		t.Fatalf("sent %d mails before SendQueued, want 0", len(mails))
+	}
+	if err := notifications.SendQueued(); err != nil {
+		t.Fatal(err)
+	}
+	mails := mailer.take()
+	if len(mails) != 1 {
+		t.Fatalf("sent %d mails, want 1", len(mails))
+	}
+	if !strings.Contains(mails[0].Body, receipt.Commitment) {
+		t.Errorf("body does not contain the receipt %s:\n%s", receipt.Commitment, mails[0].Body)
+	}
+}
+
+func TestNotificationsDisabled(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	voters := service.NewVoters(repo)
+	notifications := service.NewNotifications(repo, nil, service.NotificationOptions{})
+	election := createElection(t, elections, "Alice")
+	if err := voters.Add([]string{"a@kth.se"}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := notifications.SendDue(openTime); err != nil {
+		t.Errorf("SendDue() = %v, want nil", err)
+	}
+	notifications.VoteRecorded("a@kth.se", "", service.NewReceipt(election.ID, "a@kth.se", "secret", nil, openTime))
+	if err := notifications.SendQueued(); err != nil {
+		t.Errorf("SendQueued() = %v, want nil", err)
+	}
+}
+
+func TestAddVotersInvalidLocale(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	voters := service.NewVoters(repo)
+	if err := voters.Add([]string{"a@kth.se"}, "fi", nil); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("Add() = %v, want ErrInvalid", err)
+	}
+}
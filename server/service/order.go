@@ -0,0 +1,98 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+
+	database "durn/server/db"
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// nextPosition returns the position after all the given candidates
+func nextPosition(candidates []database.Candidate) int {
+	position := 0
+	for _, candidate := range candidates {
+		if !candidate.Symbolic && candidate.Position > position {
+			position = candidate.Position
+		}
+	}
+	return position + 1
+}
+
+// OrderCandidates sets the positions of the candidates of an election to the
+// given order, which has to contain every candidate that is not symbolic.
+// Symbolic candidates are always placed last
+func (s *Elections) OrderCandidates(electionId uuid.UUID, order []uuid.UUID) (database.Election, error) {
+	election, err := getElection(s.repo, electionId)
+	if err != nil {
+		return election, err
+	}
+	if election.Finalized {
+		return election, invalid("Can't reorder candidates of finalized election")
+	}
+
+	var candidates []uuid.UUID
+	for _, candidate := range election.Candidates {
+		if !candidate.Symbolic {
+			candidates = append(candidates, candidate.ID)
+		}
+	}
+	if !util.SameSet(candidates, order) {
+		return election, invalid("The order must contain every candidate exactly once")
+	}
+
+	positions := map[uuid.UUID]int{}
+	for i, id := range order {
+		positions[id] = i + 1
+	}
+	err = s.repo.Transaction(func(repo Repository) error {
+		for i := range election.Candidates {
+			candidate := &election.Candidates[i]
+			if candidate.Symbolic {
+				continue
+			}
+			candidate.Position = positions[candidate.ID]
+			if err := repo.SaveCandidate(candidate); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return election, err
+	}
+	return getElection(s.repo, electionId)
+}
+
+// BallotOrder returns the candidates of an election in the order they are
+// shown to the voter. For elections with ShuffleCandidates the candidates that
+// are not symbolic are shuffled, using the voter and election as seed so that
+// each voter always sees the same order. Otherwise the candidates are ordered
+// by position. Assumes the candidates of the election are ordered by position
+func BallotOrder(election database.Election, voter string) []database.Candidate {
+	candidates := make([]database.Candidate, len(election.Candidates))
+	copy(candidates, election.Candidates)
+	if !election.ShuffleCandidates {
+		return candidates
+	}
+
+	var running []database.Candidate
+	var symbolic []database.Candidate
+	for _, candidate := range candidates {
+		if candidate.Symbolic {
+			symbolic = append(symbolic, candidate)
+		} else {
+			running = append(running, candidate)
+		}
+	}
+
+	seed := sha256.Sum256(append(election.ID.Bytes(), []byte(":ballot-order:"+voter)...))
+	r := rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(seed[:8]))))
+	r.Shuffle(len(running), func(i, j int) {
+		running[i], running[j] = running[j], running[i]
+	})
+	return append(running, symbolic...)
+}
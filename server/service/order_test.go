@@ -0,0 +1,95 @@
+package service_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	database "durn/server/db"
+	"durn/server/service"
+	"durn/server/service/servicetest"
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+func candidateNames(candidates []database.Candidate) []string {
+	var names []string
+	for _, candidate := range candidates {
+		names = append(names, candidate.Name)
+	}
+	return names
+}
+
+func TestCandidatePositions(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	election := createElection(t, elections, "Carol", "Alice", "Bob")
+
+	// Candidates are placed in the order they were added, symbolic last
+	want := []string{"Carol", "Alice", "Bob", util.VacantCandidate}
+	if got := candidateNames(election.Candidates); !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates = %v, want %v", got, want)
+	}
+
+	ids := map[string]uuid.UUID{}
+	for _, candidate := range election.Candidates {
+		ids[candidate.Name] = candidate.ID
+	}
+	election, err := elections.OrderCandidates(election.ID, []uuid.UUID{ids["Alice"], ids["Bob"], ids["Carol"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"Alice", "Bob", "Carol", util.VacantCandidate}
+	if got := candidateNames(election.Candidates); !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates after ordering = %v, want %v", got, want)
+	}
+
+	for _, order := range [][]uuid.UUID{
+		{ids["Alice"], ids["Bob"]},
+		{ids["Alice"], ids["Bob"], ids["Carol"], ids[util.VacantCandidate]},
+		{ids["Alice"], ids["Bob"], ids["Bob"]},
+	} {
+		if _, err := elections.OrderCandidates(election.ID, order); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("OrderCandidates(%v) = %v, want an ErrInvalid error", order, err)
+		}
+	}
+}
+
+func TestBallotOrder(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	election := createElection(t, elections, "A", "B", "C", "D", "E", "F")
+
+	if got := service.BallotOrder(election, "voter@kth.se"); !reflect.DeepEqual(got, election.Candidates) {
+		t.Errorf("BallotOrder() without shuffling = %v, want the order by position", candidateNames(got))
+	}
+
+	shuffle := true
+	election, err := elections.Edit(election.ID, service.ElectionChanges{ShuffleCandidates: &shuffle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	election, _ = elections.Get(election.ID)
+
+	first := candidateNames(service.BallotOrder(election, "voter@kth.se"))
+	if again := candidateNames(service.BallotOrder(election, "voter@kth.se")); !reflect.DeepEqual(first, again) {
+		t.Errorf("order changed between calls: %v and %v", first, again)
+	}
+	if first[len(first)-1] != util.VacantCandidate {
+		t.Errorf("symbolic candidate was not placed last: %v", first)
+	}
+
+	orders := map[string]bool{}
+	for _, voter := range []string{"a@kth.se", "b@kth.se", "c@kth.se", "d@kth.se", "e@kth.se"} {
+		order := candidateNames(service.BallotOrder(election, voter))
+		if !util.SameSet(order, first) {
+			t.Errorf("BallotOrder() = %v, want a permutation of %v", order, first)
+		}
+		orders[strings.Join(order, ",")] = true
+	}
+	if len(orders) < 2 {
+		t.Errorf("all voters got the same order")
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// ErrNoProxy is returned when voting on behalf of someone without a valid
+// proxy from them
+var ErrNoProxy = invalid("You don't hold a valid proxy from that voter for the election")
+
+// ErrGrantorVoted is returned when voting on behalf of someone who has already
+// voted themselves, since their own vote takes precedence over the proxy
+var ErrGrantorVoted = invalid("The voter has already voted themselves, which takes precedence over the proxy")
+
+// Proxies manages the proxy registry, which lets members vote on behalf of
+// others (fullmakt)
+type Proxies struct {
+	repo Repository
+	// maxProxies is how many proxies one person may hold at the same time
+	maxProxies int
+}
+
+func NewProxies(repo Repository, maxProxies int) *Proxies {
+	return &Proxies{repo: repo, maxProxies: maxProxies}
+}
+
+// ProxyParams are the fields of a new proxy
+type ProxyParams struct {
+	Grantor    string     `json:"grantor" binding:"required"`
+	Holder     string     `json:"holder" binding:"required"`
+	ElectionID *uuid.UUID `json:"election"`
+	ValidFrom  time.Time  `json:"validFrom" binding:"required"`
+	ValidUntil time.Time  `json:"validUntil" binding:"required"`
+}
+
+// List fetches all proxies
+func (s *Proxies) List() ([]database.Proxy, error) {
+	return s.repo.ListProxies()
+}
+
+// proxiesOverlap reports whether two proxies can be used at the same time
+// in the same election
+func proxiesOverlap(a database.Proxy, b database.Proxy) bool {
+	sameScope := a.ElectionID == nil || b.ElectionID == nil || *a.ElectionID == *b.ElectionID
+	return sameScope && a.ValidFrom.Before(b.ValidUntil) && b.ValidFrom.Before(a.ValidUntil)
+}
+
+// Add registers a proxy from the grantor to the holder. A voter can only
+// have given one proxy at a time, a proxy can't be passed on, and nobody
+// can hold more than the maximum amount of proxies at the same time
+func (s *Proxies) Add(params ProxyParams) (database.Proxy, error) {
+	proxy := database.Proxy{
+		ID:         uuid.NewV4(),
+		Grantor:    params.Grantor,
+		Holder:     params.Holder,
+		ElectionID: params.ElectionID,
+		ValidFrom:  params.ValidFrom,
+		ValidUntil: params.ValidUntil,
+	}
+
+	if !util.ValidEmail(params.Grantor) || !util.ValidEmail(params.Holder) {
+		return proxy, invalid("Invalid email specified")
+	}
+	if params.Grantor == params.Holder {
+		return proxy, invalid("Voters can't hold their own proxy")
+	}
+	if !params.ValidUntil.After(params.ValidFrom) {
+		return proxy, invalid("A proxy must be valid until after it becomes valid")
+	}
+	if params.ElectionID != nil {
+		if _, err := getElection(s.repo, *params.ElectionID); err != nil {
+			return proxy, err
+		}
+	}
+
+	// The proxies given or held by the grantor and the holder
+	var existing []database.Proxy
+	for _, email := range []string{params.Grantor, params.Holder} {
+		proxies, err := s.repo.ListProxiesOf(email)
+		if err != nil {
+			return proxy, err
+		}
+		existing = append(existing, proxies...)
+	}
+	held := map[uuid.UUID]bool{}
+	for _, other := range existing {
+		if !proxiesOverlap(proxy, other) {
+			continue
+		}
+		switch {
+		case other.Grantor == proxy.Grantor:
+			return proxy, invalid("%s has already given a proxy to %s at that time", proxy.Grantor, other.Holder)
+		case other.Holder == proxy.Grantor || other.Grantor == proxy.Holder:
+			return proxy, invalid("Proxies can't be passed on")
+		case other.Holder == proxy.Holder:
+			held[other.ID] = true
+		}
+	}
+	if len(held) >= s.maxProxies {
+		return proxy, invalid("%s can't hold more than %d proxies at the same time", proxy.Holder, s.maxProxies)
+	}
+
+	if err := s.repo.CreateProxy(&proxy); err != nil {
+		return proxy, err
+	}
+	return proxy, nil
+}
+
+// Remove removes the specified proxy
+func (s *Proxies) Remove(id uuid.UUID) error {
+	err := s.repo.DeleteProxy(id)
+	if errors.Is(err, ErrNotFound) {
+		return invalid("Invalid proxy specified")
+	}
+	return err
+}
+
+// Held fetches the proxies the user holds that are valid at the given time
+func (s *Proxies) Held(holder string, now time.Time) ([]database.Proxy, error) {
+	proxies, err := s.repo.ListProxiesOf(holder)
+	if err != nil {
+		return nil, err
+	}
+	result := []database.Proxy{}
+	for _, proxy := range proxies {
+		if proxy.Holder == holder && !now.Before(proxy.ValidFrom) && now.Before(proxy.ValidUntil) {
+			result = append(result, proxy)
+		}
+	}
+	return result, nil
+}
+
+// Authorize checks that the holder may vote on behalf of the grantor in the
+// election at the given time, which requires a valid proxy and that the
+// grantor is in the voter roll
+func (s *Proxies) Authorize(holder string, grantor string, electionId uuid.UUID, now time.Time) error {
+	proxies, err := s.Held(holder, now)
+	if err != nil {
+		return err
+	}
+	for _, proxy := range proxies {
+		if proxy.Grantor != grantor || (proxy.ElectionID != nil && *proxy.ElectionID != electionId) {
+			continue
+		}
+		isVoter, err := s.repo.IsVoter(grantor)
+		if err != nil {
+			return err
+		}
+		if !isVoter {
+			return ErrNoProxy
+		}
+		return nil
+	}
+	return ErrNoProxy
+}
@@ -0,0 +1,168 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"durn/server/service"
+	"durn/server/service/servicetest"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+func TestAddProxy(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	proxies := service.NewProxies(repo, 2)
+	election := createElection(t, elections, "Alice")
+	other := createElection(t, elections, "Bob")
+
+	meeting := func(grantor string, holder string, electionId *uuid.UUID) service.ProxyParams {
+		return service.ProxyParams{
+			Grantor:    grantor,
+			Holder:     holder,
+			ElectionID: electionId,
+			ValidFrom:  openTime,
+			ValidUntil: closeTime,
+		}
+	}
+	for _, params := range []service.ProxyParams{
+		meeting("a@kth.se", "h@kth.se", &election.ID),
+		meeting("b@kth.se", "h@kth.se", nil),
+		// Proxies in other elections do not count towards the limit
+		meeting("c@kth.se", "h@kth.se", &other.ID),
+	} {
+		if _, err := proxies.Add(params); err != nil {
+			t.Fatalf("Add(%+v) = %v", params, err)
+		}
+	}
+	later := meeting("d@kth.se", "h@kth.se", &election.ID)
+	later.ValidFrom, later.ValidUntil = closeTime, closeTime.Add(time.Hour)
+	if _, err := proxies.Add(later); err != nil {
+		t.Errorf("Add() after the other proxies = %v", err)
+	}
+
+	unknown := uuid.NewV4()
+	invalid := map[string]service.ProxyParams{
+		"too many proxies":   meeting("e@kth.se", "h@kth.se", &election.ID),
+		"second proxy":       meeting("a@kth.se", "i@kth.se", nil),
+		"passed on":          meeting("h@kth.se", "i@kth.se", &election.ID),
+		"holder has granted": meeting("e@kth.se", "a@kth.se", &election.ID),
+		"own proxy":          meeting("e@kth.se", "e@kth.se", nil),
+		"invalid email":      meeting("e", "i@kth.se", nil),
+		"unknown election":   meeting("e@kth.se", "i@kth.se", &unknown),
+		"empty window":       {Grantor: "e@kth.se", Holder: "i@kth.se", ValidFrom: closeTime, ValidUntil: openTime},
+	}
+	for name, params := range invalid {
+		if _, err := proxies.Add(params); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("Add() with %s = %v, want ErrInvalid", name, err)
+		}
+	}
+}
+
+func TestProxyVote(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	voters := service.NewVoters(repo)
+	proxies := service.NewProxies(repo, 1)
+	election := createElection(t, elections, "Alice", "Bob")
+	other := createElection(t, elections, "Carol")
+	if err := voters.Add([]string{"a@kth.se", "h@kth.se"}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := proxies.Add(service.ProxyParams{
+		Grantor:    "a@kth.se",
+		Holder:     "h@kth.se",
+		ElectionID: &election.ID,
+		ValidFrom:  openTime,
+		ValidUntil: openTime.Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := proxies.Add(service.ProxyParams{
+		Grantor:    "b@kth.se",
+		Holder:     "i@kth.se",
+		ValidFrom:  openTime,
+		ValidUntil: closeTime,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := openTime.Add(time.Minute)
+	if err := proxies.Authorize("h@kth.se", "a@kth.se", election.ID, now); err != nil {
+		t.Errorf("Authorize() = %v", err)
+	}
+	tests := []struct {
+		name     string
+		holder   string
+		grantor  string
+		election uuid.UUID
+		time     time.Time
+	}{
+		{"before valid", "h@kth.se", "a@kth.se", election.ID, openTime.Add(-time.Minute)},
+		{"after valid", "h@kth.se", "a@kth.se", election.ID, openTime.Add(time.Hour)},
+		{"other election", "h@kth.se", "a@kth.se", other.ID, now},
+		{"other holder", "i@kth.se", "a@kth.se", election.ID, now},
+		{"reversed", "a@kth.se", "h@kth.se", election.ID, now},
+		{"grantor not a voter", "i@kth.se", "b@kth.se", election.ID, now},
+	}
+	for _, test := range tests {
+		if err := proxies.Authorize(test.holder, test.grantor, test.election, test.time); !errors.Is(err, service.ErrNoProxy) {
+			t.Errorf("Authorize() %s = %v, want ErrNoProxy", test.name, err)
+		}
+	}
+
+	held, err := proxies.Held("h@kth.se", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(held) != 1 || held[0].Grantor != "a@kth.se" {
+		t.Errorf("Held() = %+v, want the proxy from a@kth.se", held)
+	}
+}
+
+func TestProxyVotePrecedence(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob")
+	ballot := ballotOf(election.Candidates)
+	reversed := service.Ballot{ballot[2], ballot[1], ballot[0]}
+	now := openTime.Add(time.Minute)
+
+	// The holder can change the vote they cast with the proxy
+	for _, b := range []service.Ballot{ballot, reversed} {
+		if _, err := votes.CastByProxy(election.ID, "h@kth.se", "a@kth.se", "", b, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The grantor's own vote replaces the proxy vote, and can't be replaced
+	// by the holder afterwards
+	if _, err := votes.Cast(election.ID, "a@kth.se", "", ballot, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := votes.CastByProxy(election.ID, "h@kth.se", "a@kth.se", "", reversed, now); !errors.Is(err, service.ErrGrantorVoted) {
+		t.Errorf("CastByProxy() after the grantor voted = %v, want ErrGrantorVoted", err)
+	}
+	// The same holds if the grantor votes first
+	if _, err := votes.Cast(election.ID, "b@kth.se", "", ballot, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := votes.CastByProxy(election.ID, "i@kth.se", "b@kth.se", "", reversed, now); !errors.Is(err, service.ErrGrantorVoted) {
+		t.Errorf("CastByProxy() after the grantor voted first = %v, want ErrGrantorVoted", err)
+	}
+
+	list, err := votes.List(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("got %d votes, want one for each grantor", len(list))
+	}
+	for _, vote := range list {
+		if vote.Rankings[0] != ballot[0] {
+			t.Errorf("vote ranks %v first, want the grantor's own ballot", vote.Rankings[0])
+		}
+	}
+}
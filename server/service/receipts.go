@@ -0,0 +1,116 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/crypto/sha3"
+)
+
+// Receipt is given to voters when their vote is recorded, and is signed by
+// the server so that it proves that the vote was accepted
+type Receipt struct {
+	Election   uuid.UUID `json:"election"`
+	Time       time.Time `json:"time"`
+	Commitment string    `json:"commitment"`
+	Signature  string    `json:"signature"`
+}
+
+// NewReceipt creates the unsigned receipt of a vote. The commitment is
+// sha3-256 of "[user-email]_[secret]_[election-id]" followed by
+// "_[rank]:[candidate-id]" for each candidate in the ballot, so only the
+// voter, who knows the secret, can tell which vote it is for
+func NewReceipt(electionId uuid.UUID, email string, secret string, ballot Ballot, now time.Time) Receipt {
+	var voteString strings.Builder
+	fmt.Fprintf(&voteString, "%s_%s_%s", email, secret, electionId)
+	for rank, candidate := range ballot {
+		fmt.Fprintf(&voteString, "_%d:%s", rank, candidate)
+	}
+	commitment := sha3.Sum256([]byte(voteString.String()))
+	return Receipt{
+		Election:   electionId,
+		Time:       now.UTC(),
+		Commitment: hex.EncodeToString(commitment[:]),
+	}
+}
+
+// signedData is what the signature of the receipt is made over,
+// "durn-receipt:[election-id]:[time]:[commitment]" with the time in UTC as
+// RFC 3339 with nanoseconds
+func (r Receipt) signedData() []byte {
+	return []byte(fmt.Sprintf("durn-receipt:%s:%s:%s", r.Election, r.Time.UTC().Format(time.RFC3339Nano), r.Commitment))
+}
+
+// ReceiptSigner signs receipts with an Ed25519 key
+type ReceiptSigner struct {
+	key ed25519.PrivateKey
+}
+
+func NewReceiptSigner(key ed25519.PrivateKey) *ReceiptSigner {
+	return &ReceiptSigner{key: key}
+}
+
+// GenerateReceiptKey creates a new random signing key
+func GenerateReceiptKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	return key, err
+}
+
+// ParseReceiptKey parses a base64 encoded Ed25519 key, either the 32 byte
+// seed or the 64 byte private key
+func ParseReceiptKey(encoded string) (ed25519.PrivateKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid receipt key: %w", err)
+	}
+	switch len(data) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(data), nil
+	case ed25519.PrivateKeySize:
+		key := ed25519.PrivateKey(data)
+		if !key.Public().(ed25519.PublicKey).Equal(ed25519.NewKeyFromSeed(key.Seed()).Public()) {
+			return nil, errors.New("invalid receipt key: public part does not match the seed")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("invalid receipt key: %d bytes, want %d or %d", len(data), ed25519.SeedSize, ed25519.PrivateKeySize)
+	}
+}
+
+// EncodeReceiptKey encodes the seed of the key as read by ParseReceiptKey
+func EncodeReceiptKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Seed())
+}
+
+// PublicKey returns the key that signatures are verified with
+func (s *ReceiptSigner) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign returns the receipt with its signature set
+func (s *ReceiptSigner) Sign(receipt Receipt) Receipt {
+	receipt.Time = receipt.Time.UTC()
+	receipt.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, receipt.signedData()))
+	return receipt
+}
+
+// Verify checks that the receipt was signed by this signer
+func (s *ReceiptSigner) Verify(receipt Receipt) bool {
+	return VerifyReceipt(s.PublicKey(), receipt)
+}
+
+// VerifyReceipt checks that the receipt was signed by the key
+func VerifyReceipt(key ed25519.PublicKey, receipt Receipt) bool {
+	signature, err := base64.StdEncoding.DecodeString(receipt.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(key, receipt.signedData(), signature)
+}
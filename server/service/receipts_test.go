@@ -0,0 +1,90 @@
+package service_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"durn/server/service"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+func TestReceipt(t *testing.T) {
+	electionId := uuid.NewV4()
+	ballot := service.Ballot{uuid.NewV4(), uuid.NewV4()}
+	receipt := service.NewReceipt(electionId, "a@kth.se", "secret", ballot, openTime)
+	if again := service.NewReceipt(electionId, "a@kth.se", "secret", ballot, openTime); again != receipt {
+		t.Errorf("receipts of the same vote differ: %v, %v", receipt, again)
+	}
+	reversed := service.Ballot{ballot[1], ballot[0]}
+	if other := service.NewReceipt(electionId, "a@kth.se", "secret", reversed, openTime); other.Commitment == receipt.Commitment {
+		t.Error("receipts of different votes are equal")
+	}
+}
+
+func TestReceiptSignature(t *testing.T) {
+	key, err := service.GenerateReceiptKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := service.NewReceiptSigner(key)
+	local := time.FixedZone("CET", 60*60)
+	receipt := signer.Sign(service.NewReceipt(uuid.NewV4(), "a@kth.se", "", nil, openTime.In(local).Add(123*time.Nanosecond)))
+
+	// The receipt is verified after being sent to the voter as JSON
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received service.Receipt
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+	if !signer.Verify(received) {
+		t.Fatalf("signed receipt %s did not verify", data)
+	}
+
+	tampered := []func(r *service.Receipt){
+		func(r *service.Receipt) { r.Election = uuid.NewV4() },
+		func(r *service.Receipt) { r.Time = r.Time.Add(time.Second) },
+		func(r *service.Receipt) { r.Commitment = "00" + r.Commitment[2:] },
+		func(r *service.Receipt) { r.Signature = "" },
+		func(r *service.Receipt) { r.Signature = "not base64" },
+	}
+	for i, tamper := range tampered {
+		r := received
+		tamper(&r)
+		if signer.Verify(r) {
+			t.Errorf("tampered receipt %d verified", i)
+		}
+	}
+
+	other, err := service.GenerateReceiptKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.VerifyReceipt(service.NewReceiptSigner(other).PublicKey(), received) {
+		t.Error("receipt verified with another key")
+	}
+}
+
+func TestParseReceiptKey(t *testing.T) {
+	key, err := service.GenerateReceiptKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := service.ParseReceiptKey(service.EncodeReceiptKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Equal(key) {
+		t.Error("parsed key differs from the encoded key")
+	}
+
+	for _, encoded := range []string{"", "not base64", "c2hvcnQ="} {
+		if _, err := service.ParseReceiptKey(encoded); err == nil {
+			t.Errorf("ParseReceiptKey(%q) accepted an invalid key", encoded)
+		}
+	}
+}
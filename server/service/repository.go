@@ -0,0 +1,410 @@
+package service
+
+import (
+	"errors"
+
+	database "durn/server/db"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotFound is returned by repositories when a requested record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// Repository is the storage used by the services
+type Repository interface {
+	// Transaction runs fn with a repository where all changes are made in a
+	// single transaction, which is rolled back if fn returns an error
+	Transaction(fn func(repo Repository) error) error
+
+	// CreateElection creates an election together with its candidates
+	CreateElection(election *database.Election) error
+	// GetElection fetches an election including its candidates, ordered by
+	// their position on the ballot
+	GetElection(id uuid.UUID) (database.Election, error)
+	// GetElectionWithVotes fetches an election including its candidates and
+	// all votes with their rankings
+	GetElectionWithVotes(id uuid.UUID) (database.Election, error)
+	// ListElections fetches all elections including their candidates
+	ListElections() ([]database.Election, error)
+	SaveElection(election *database.Election) error
+	// DeleteElection deletes an election and its candidates
+	DeleteElection(id uuid.UUID) error
+	// DeleteAllElections deletes all elections, candidates and votes
+	DeleteAllElections() error
+
+	CreateCandidate(candidate *database.Candidate) error
+	// GetCandidate fetches a candidate including its election
+	GetCandidate(id uuid.UUID) (database.Candidate, error)
+	SaveCandidate(candidate *database.Candidate) error
+	DeleteCandidate(id uuid.UUID) error
+
+	// FindVoteByUserHash fetches the vote with the given user hash, returning
+	// ErrNotFound if there is none
+	FindVoteByUserHash(hash string) (database.Vote, error)
+	CreateVote(vote *database.Vote) error
+	SaveVote(vote *database.Vote) error
+	// ReplaceRankings replaces all rankings of a vote
+	ReplaceRankings(voteId uuid.UUID, rankings []database.Ranking) error
+	CreateCastedVote(castedVote *database.CastedVote) error
+	// GetCastedVote fetches the record of the voter having voted in the
+	// election, or ErrNotFound if they have not
+	GetCastedVote(email string, electionId uuid.UUID) (database.CastedVote, error)
+	SaveCastedVote(castedVote *database.CastedVote) error
+	HasCastedVote(email string, electionId uuid.UUID) (bool, error)
+	// ListCastedVotes fetches the records of who has voted in an election
+	ListCastedVotes(electionId uuid.UUID) ([]database.CastedVote, error)
+	// ListVotes fetches all votes of an election including their rankings
+	ListVotes(electionId uuid.UUID) ([]database.Vote, error)
+	CountVotes(electionId uuid.UUID) (int64, error)
+	// ListVoteHashes fetches the receipt commitments of the votes of an election
+	ListVoteHashes(electionId uuid.UUID) ([]database.VoteHash, error)
+	// SaveVoteHash creates or replaces the receipt commitment of a vote
+	SaveVoteHash(hash *database.VoteHash) error
+	// SaveEncryptedBallot creates or replaces the encrypted ballot of a vote
+	SaveEncryptedBallot(ballot *database.EncryptedBallot) error
+	ListEncryptedBallots(electionId uuid.UUID) ([]database.EncryptedBallot, error)
+
+	// AddVoters adds voters, updating the locale of voters that already
+	// exist, and their weight if updateWeight is set
+	AddVoters(voters []database.ValidVoter, updateWeight bool) error
+	RemoveVoters(emails []string) error
+	ListVoters() ([]database.ValidVoter, error)
+	IsVoter(email string) (bool, error)
+	GetVoter(email string) (database.ValidVoter, error)
+
+	ListRoles() ([]database.Role, error)
+	ListRolesOf(email string) ([]database.Role, error)
+	CreateRole(role *database.Role) error
+	// DeleteRole deletes a role, returning ErrNotFound if there is none
+	DeleteRole(id uuid.UUID) error
+
+	ListProxies() ([]database.Proxy, error)
+	// ListProxiesOf fetches the proxies the user has given or holds
+	ListProxiesOf(email string) ([]database.Proxy, error)
+	CreateProxy(proxy *database.Proxy) error
+	// DeleteProxy deletes a proxy, returning ErrNotFound if there is none
+	DeleteProxy(id uuid.UUID) error
+
+	// ListSentNotifications fetches the notifications of a kind that have
+	// been sent for an election
+	ListSentNotifications(electionId uuid.UUID, kind string) ([]database.SentNotification, error)
+	CreateSentNotification(notification *database.SentNotification) error
+}
+
+// GormRepository is a Repository storing everything in a database through gorm
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+// notFound converts gorm's not found error to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ballotOrder orders preloaded candidates as they are placed on the ballot
+func ballotOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("symbolic, position, name")
+}
+
+func (r *GormRepository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GormRepository{db: tx})
+	})
+}
+
+func (r *GormRepository) CreateElection(election *database.Election) error {
+	return r.db.Create(election).Error
+}
+
+func (r *GormRepository) GetElection(id uuid.UUID) (database.Election, error) {
+	election := database.Election{ID: id}
+	err := r.db.Preload("Candidates", ballotOrder).First(&election).Error
+	return election, notFound(err)
+}
+
+func (r *GormRepository) GetElectionWithVotes(id uuid.UUID) (database.Election, error) {
+	election := database.Election{ID: id}
+	err := r.db.Preload("Votes.Rankings").Preload("Candidates", ballotOrder).First(&election).Error
+	return election, notFound(err)
+}
+
+func (r *GormRepository) ListElections() ([]database.Election, error) {
+	var elections []database.Election
+	err := r.db.Preload("Candidates", ballotOrder).Find(&elections).Error
+	return elections, err
+}
+
+func (r *GormRepository) SaveElection(election *database.Election) error {
+	return r.db.Save(election).Error
+}
+
+func (r *GormRepository) DeleteElection(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("election_id = ?", id).Delete(&database.Candidate{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&database.Election{ID: id}).Error
+	})
+}
+
+func (r *GormRepository) DeleteAllElections() error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1=1").Delete(&database.Ranking{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1=1").Delete(&database.Vote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1=1").Delete(&database.VoteHash{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1=1").Delete(&database.EncryptedBallot{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1=1").Delete(&database.Candidate{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1=1").Delete(&database.CastedVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("election_id IS NOT NULL").Delete(&database.Role{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("election_id IS NOT NULL").Delete(&database.Proxy{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1=1").Delete(&database.SentNotification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1=1").Delete(&database.Election{}).Error; err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *GormRepository) CreateCandidate(candidate *database.Candidate) error {
+	return r.db.Create(candidate).Error
+}
+
+func (r *GormRepository) GetCandidate(id uuid.UUID) (database.Candidate, error) {
+	candidate := database.Candidate{ID: id}
+	err := r.db.Preload("Election").First(&candidate).Error
+	return candidate, notFound(err)
+}
+
+func (r *GormRepository) SaveCandidate(candidate *database.Candidate) error {
+	return r.db.Omit("Election").Save(candidate).Error
+}
+
+func (r *GormRepository) DeleteCandidate(id uuid.UUID) error {
+	return r.db.Delete(&database.Candidate{ID: id}).Error
+}
+
+func (r *GormRepository) FindVoteByUserHash(hash string) (database.Vote, error) {
+	var votes []database.Vote
+	if err := r.db.Where("user_hash = ?", hash).Limit(1).Find(&votes).Error; err != nil {
+		return database.Vote{}, err
+	}
+	if len(votes) == 0 {
+		return database.Vote{}, ErrNotFound
+	}
+	return votes[0], nil
+}
+
+func (r *GormRepository) CreateVote(vote *database.Vote) error {
+	return r.db.Omit("Rankings").Create(vote).Error
+}
+
+func (r *GormRepository) SaveVote(vote *database.Vote) error {
+	return r.db.Omit("Rankings").Save(vote).Error
+}
+
+func (r *GormRepository) ReplaceRankings(voteId uuid.UUID, rankings []database.Ranking) error {
+	if err := r.db.Delete(&database.Ranking{}, "vote_id = ?", voteId).Error; err != nil {
+		return err
+	}
+	if len(rankings) == 0 {
+		return nil
+	}
+	return r.db.Create(&rankings).Error
+}
+
+func (r *GormRepository) CreateCastedVote(castedVote *database.CastedVote) error {
+	return r.db.Omit("User", "Election").Create(castedVote).Error
+}
+
+func (r *GormRepository) GetCastedVote(email string, electionId uuid.UUID) (database.CastedVote, error) {
+	var castedVote database.CastedVote
+	err := r.db.Where("email = ? AND election_id = ?", email, electionId).First(&castedVote).Error
+	return castedVote, notFound(err)
+}
+
+func (r *GormRepository) SaveCastedVote(castedVote *database.CastedVote) error {
+	return r.db.Omit("User", "Election").Save(castedVote).Error
+}
+
+func (r *GormRepository) HasCastedVote(email string, electionId uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&database.CastedVote{}).
+		Where("email = ? AND election_id = ?", email, electionId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *GormRepository) ListCastedVotes(electionId uuid.UUID) ([]database.CastedVote, error) {
+	var castedVotes []database.CastedVote
+	err := r.db.Where("election_id = ?", electionId).Find(&castedVotes).Error
+	return castedVotes, err
+}
+
+func (r *GormRepository) ListVotes(electionId uuid.UUID) ([]database.Vote, error) {
+	var votes []database.Vote
+	err := r.db.Preload("Rankings").Find(&votes, "election_id = ?", electionId).Error
+	return votes, err
+}
+
+func (r *GormRepository) CountVotes(electionId uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&database.Vote{}).Where("election_id = ?", electionId).Count(&count).Error
+	return count, err
+}
+
+func (r *GormRepository) ListVoteHashes(electionId uuid.UUID) ([]database.VoteHash, error) {
+	var hashes []database.VoteHash
+	err := r.db.Where("election_id = ?", electionId).Find(&hashes).Error
+	return hashes, err
+}
+
+func (r *GormRepository) SaveVoteHash(hash *database.VoteHash) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "vote_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hash"}),
+	}).Create(hash).Error
+}
+
+func (r *GormRepository) SaveEncryptedBallot(ballot *database.EncryptedBallot) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "vote_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"ciphertext"}),
+	}).Create(ballot).Error
+}
+
+func (r *GormRepository) ListEncryptedBallots(electionId uuid.UUID) ([]database.EncryptedBallot, error) {
+	var ballots []database.EncryptedBallot
+	err := r.db.Where("election_id = ?", electionId).Find(&ballots).Error
+	return ballots, err
+}
+
+func (r *GormRepository) AddVoters(voters []database.ValidVoter, updateWeight bool) error {
+	if len(voters) == 0 {
+		return nil
+	}
+	columns := []string{"locale"}
+	if updateWeight {
+		columns = append(columns, "weight")
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(&voters).Error
+}
+
+func (r *GormRepository) RemoveVoters(emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	return r.db.Where("email IN ?", emails).Delete(&database.ValidVoter{}).Error
+}
+
+func (r *GormRepository) ListVoters() ([]database.ValidVoter, error) {
+	var voters []database.ValidVoter
+	err := r.db.Find(&voters).Error
+	return voters, err
+}
+
+func (r *GormRepository) IsVoter(email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&database.ValidVoter{}).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *GormRepository) GetVoter(email string) (database.ValidVoter, error) {
+	var voter database.ValidVoter
+	err := r.db.Where("email = ?", email).First(&voter).Error
+	return voter, notFound(err)
+}
+
+func (r *GormRepository) ListRoles() ([]database.Role, error) {
+	roles := []database.Role{}
+	err := r.db.Order("email").Find(&roles).Error
+	return roles, err
+}
+
+func (r *GormRepository) ListRolesOf(email string) ([]database.Role, error) {
+	var roles []database.Role
+	err := r.db.Where("email = ?", email).Find(&roles).Error
+	return roles, err
+}
+
+func (r *GormRepository) CreateRole(role *database.Role) error {
+	return r.db.Create(role).Error
+}
+
+func (r *GormRepository) DeleteRole(id uuid.UUID) error {
+	res := r.db.Delete(&database.Role{ID: id})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormRepository) ListProxies() ([]database.Proxy, error) {
+	proxies := []database.Proxy{}
+	err := r.db.Order("valid_from").Find(&proxies).Error
+	return proxies, err
+}
+
+func (r *GormRepository) ListProxiesOf(email string) ([]database.Proxy, error) {
+	var proxies []database.Proxy
+	err := r.db.Where("grantor = ? OR holder = ?", email, email).Order("valid_from").Find(&proxies).Error
+	return proxies, err
+}
+
+func (r *GormRepository) CreateProxy(proxy *database.Proxy) error {
+	return r.db.Create(proxy).Error
+}
+
+func (r *GormRepository) DeleteProxy(id uuid.UUID) error {
+	res := r.db.Delete(&database.Proxy{ID: id})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormRepository) ListSentNotifications(electionId uuid.UUID, kind string) ([]database.SentNotification, error) {
+	var notifications []database.SentNotification
+	err := r.db.Where("election_id = ? AND kind = ?", electionId, kind).Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *GormRepository) CreateSentNotification(notification *database.SentNotification) error {
+	return r.db.Create(notification).Error
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+
+	database "durn/server/db"
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Permissions that can be granted through roles
+const (
+	AdminReadPermission  = "admin-read"
+	AdminWritePermission = "admin-write"
+	// ObserverPermission allows viewing data associated with a single election,
+	// and is only valid when scoped to that election
+	ObserverPermission = "observer"
+)
+
+// Roles manages the local role store
+type Roles struct {
+	repo Repository
+}
+
+func NewRoles(repo Repository) *Roles {
+	return &Roles{repo: repo}
+}
+
+// RoleParams are the fields of a new role
+type RoleParams struct {
+	Email      string     `json:"email" binding:"required"`
+	Permission string     `json:"permission" binding:"required"`
+	ElectionID *uuid.UUID `json:"election"`
+}
+
+// List fetches all roles
+func (s *Roles) List() ([]database.Role, error) {
+	return s.repo.ListRoles()
+}
+
+// Of fetches all roles of a user
+func (s *Roles) Of(email string) ([]database.Role, error) {
+	return s.repo.ListRolesOf(email)
+}
+
+// Add grants a permission to a user. If an election is specified the
+// permission is only valid for that election.
+// Allowed permissions are admin-read, admin-write and observer, where
+// observer requires an election.
+func (s *Roles) Add(params RoleParams) (database.Role, error) {
+	role := database.Role{
+		ID:         uuid.NewV4(),
+		Email:      params.Email,
+		Permission: params.Permission,
+		ElectionID: params.ElectionID,
+	}
+
+	if !util.ValidEmail(params.Email) {
+		return role, invalid("Invalid email specified")
+	}
+	switch params.Permission {
+	case AdminReadPermission, AdminWritePermission:
+	case ObserverPermission:
+		if params.ElectionID == nil {
+			return role, invalid("Observers must be given an election")
+		}
+	default:
+		return role, invalid("Unknown permission '%s'", params.Permission)
+	}
+
+	if params.ElectionID != nil {
+		if _, err := getElection(s.repo, *params.ElectionID); err != nil {
+			return role, err
+		}
+	}
+
+	if err := s.repo.CreateRole(&role); err != nil {
+		return role, err
+	}
+	return role, nil
+}
+
+// Remove removes the specified role
+func (s *Roles) Remove(id uuid.UUID) error {
+	err := s.repo.DeleteRole(id)
+	if errors.Is(err, ErrNotFound) {
+		return invalid("Invalid role specified")
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+// Package servicetest provides helpers for testing code that uses the services
+package servicetest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	database "durn/server/db"
+	"durn/server/service"
+)
+
+// NewRepository returns a repository backed by an in-memory SQLite
+// database, which only lives for the duration of the test
+func NewRepository(t testing.TB) *service.GormRepository {
+	return service.NewGormRepository(NewDB(t))
+}
+
+// NewDB returns an in-memory SQLite database with all tables created,
+// which only lives for the duration of the test. The migrations are
+// written for postgres, so the tables are created from the models instead,
+// and the migrations are checked against the models in the db package
+func NewDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", name)
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(database.Models...); err != nil {
+		t.Fatal(err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return db
+}
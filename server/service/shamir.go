@@ -0,0 +1,101 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+// Shamir's secret sharing over GF(2^8), byte by byte. A share is the x
+// coordinate, which is never 0, followed by the value of the polynomial of
+// each byte of the secret at x
+
+// gfMul multiplies in GF(2^8) with the polynomial x^8 + x^4 + x^3 + x + 1
+func gfMul(a byte, b byte) byte {
+	var product byte
+	for b > 0 {
+		if b&1 != 0 {
+			product ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return product
+}
+
+// gfInv returns the multiplicative inverse, a^254, of a non-zero element
+func gfInv(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 254; i++ {
+		result = gfMul(result, a)
+	}
+	return result
+}
+
+// splitSecret splits the secret into n shares, any threshold of which
+// recover it
+func splitSecret(secret []byte, n int, threshold int) ([][]byte, error) {
+	if threshold < 1 || threshold > n || n > 255 {
+		return nil, errors.New("invalid amount of shares")
+	}
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][0] = byte(i + 1)
+	}
+
+	coefficients := make([]byte, threshold)
+	for j, b := range secret {
+		coefficients[0] = b
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			// Horner's method, from the highest coefficient
+			var y byte
+			for k := threshold - 1; k >= 0; k-- {
+				y = gfMul(y, share[0]) ^ coefficients[k]
+			}
+			share[j+1] = y
+		}
+	}
+	return shares, nil
+}
+
+// combineShares recovers the secret from shares by Lagrange interpolation at
+// 0. Gives a wrong secret, without an error, if there are fewer shares than
+// the threshold
+func combineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no shares")
+	}
+	seen := map[byte]bool{}
+	for _, share := range shares {
+		if len(share) < 2 || len(share) != len(shares[0]) || share[0] == 0 {
+			return nil, errors.New("malformed share")
+		}
+		if seen[share[0]] {
+			return nil, errors.New("duplicate share")
+		}
+		seen[share[0]] = true
+	}
+
+	secret := make([]byte, len(shares[0])-1)
+	for i, share := range shares {
+		// The Lagrange basis polynomial of the share at 0, in GF(2^8)
+		// subtraction is addition, which is xor
+		basis := byte(1)
+		for j, other := range shares {
+			if i != j {
+				basis = gfMul(basis, gfMul(other[0], gfInv(other[0]^share[0])))
+			}
+		}
+		for k := range secret {
+			secret[k] ^= gfMul(share[k+1], basis)
+		}
+	}
+	return secret, nil
+}
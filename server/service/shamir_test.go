@@ -0,0 +1,52 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGFInverse(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if product := gfMul(byte(a), gfInv(byte(a))); product != 1 {
+			t.Errorf("%d * inverse = %d, want 1", a, product)
+		}
+	}
+}
+
+func TestShamir(t *testing.T) {
+	secret := []byte("the private key of the election!")
+	shares, err := splitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every set of three shares recovers the secret
+	for i := 0; i < 5; i++ {
+		for j := i + 1; j < 5; j++ {
+			for k := j + 1; k < 5; k++ {
+				combined, err := combineShares([][]byte{shares[k], shares[i], shares[j]})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(combined, secret) {
+					t.Errorf("shares %d, %d and %d gave %q", i, j, k, combined)
+				}
+			}
+		}
+	}
+
+	combined, err := combineShares(shares[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(combined, secret) {
+		t.Error("two shares recovered the secret")
+	}
+
+	if _, err := combineShares([][]byte{shares[0], shares[0], shares[1]}); err == nil {
+		t.Error("duplicate shares were accepted")
+	}
+	if _, err := combineShares([][]byte{shares[0], shares[1][:4], shares[2]}); err == nil {
+		t.Error("shares of different lengths were accepted")
+	}
+}
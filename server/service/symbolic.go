@@ -0,0 +1,104 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// newSymbolicCandidate creates an unsaved symbolic candidate of the kind,
+// named in the default locale and placed after all other candidates
+func newSymbolicCandidate(electionId uuid.UUID, kind database.SymbolicKind) database.Candidate {
+	return database.Candidate{
+		ID:           uuid.NewV4(),
+		Name:         kind.Name(util.DefaultLocale),
+		Presentation: "",
+		ElectionID:   electionId,
+		Symbolic:     true,
+		SymbolicKind: kind,
+		Position:     kind.Position(),
+	}
+}
+
+// validateSymbolicKinds checks that the kinds are known and not repeated
+func validateSymbolicKinds(kinds []database.SymbolicKind) error {
+	seen := map[database.SymbolicKind]bool{}
+	for _, kind := range kinds {
+		if !kind.Valid() {
+			var valid []string
+			for _, kind := range database.SymbolicKinds {
+				valid = append(valid, string(kind))
+			}
+			return invalid("Unknown symbolic candidate '%s', valid are %s", kind, strings.Join(valid, ", "))
+		}
+		if seen[kind] {
+			return invalid("Symbolic candidate '%s' given more than once", kind)
+		}
+		seen[kind] = true
+	}
+	return nil
+}
+
+// SetSymbolicCandidates adds and removes symbolic candidates so that the
+// election has exactly the given kinds. Like other candidates, they can only
+// be changed before the election is published, opened or has any votes
+func (s *Elections) SetSymbolicCandidates(electionId uuid.UUID, kinds []database.SymbolicKind, now time.Time) (database.Election, error) {
+	if err := validateSymbolicKinds(kinds); err != nil {
+		return database.Election{}, err
+	}
+	election, err := getElection(s.repo, electionId)
+	if err != nil {
+		return election, err
+	}
+
+	if election.Published || election.Finalized {
+		return election, invalid("Can't change symbolic candidates of published or finalized election")
+	}
+	if election.OpenTime.Valid && now.After(election.OpenTime.Time) {
+		return election, invalid("Can't change symbolic candidates of opened election")
+	}
+	votes, err := s.repo.CountVotes(electionId)
+	if err != nil {
+		return election, err
+	}
+	if votes > 0 {
+		return election, invalid("Can't change symbolic candidates of election with votes")
+	}
+
+	wanted := map[database.SymbolicKind]bool{}
+	for _, kind := range kinds {
+		wanted[kind] = true
+	}
+	err = s.repo.Transaction(func(repo Repository) error {
+		existing := map[database.SymbolicKind]bool{}
+		for _, candidate := range election.Candidates {
+			if !candidate.Symbolic {
+				continue
+			}
+			existing[candidate.SymbolicKind] = true
+			if !wanted[candidate.SymbolicKind] {
+				if err := repo.DeleteCandidate(candidate.ID); err != nil {
+					return err
+				}
+			}
+		}
+		for _, kind := range kinds {
+			if existing[kind] {
+				continue
+			}
+			candidate := newSymbolicCandidate(electionId, kind)
+			if err := repo.CreateCandidate(&candidate); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return election, err
+	}
+	return getElection(s.repo, electionId)
+}
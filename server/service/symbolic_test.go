@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/service"
+	"durn/server/service/servicetest"
+)
+
+func symbolicKinds(election database.Election) []database.SymbolicKind {
+	var kinds []database.SymbolicKind
+	for _, candidate := range election.Candidates {
+		if candidate.Symbolic {
+			kinds = append(kinds, candidate.SymbolicKind)
+		}
+	}
+	return kinds
+}
+
+func TestSymbolicCandidates(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+
+	params := service.DefaultElectionParams()
+	params.SymbolicCandidates = []database.SymbolicKind{database.SymbolicBlank, database.SymbolicVacant}
+	election, err := elections.Create(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	election, err = elections.Get(election.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kinds := symbolicKinds(election); !reflect.DeepEqual(kinds, []database.SymbolicKind{database.SymbolicVacant, database.SymbolicBlank}) {
+		t.Errorf("symbolic candidates = %v, want vacant then blank", kinds)
+	}
+
+	now := time.Now()
+	election, err = elections.SetSymbolicCandidates(election.ID, []database.SymbolicKind{database.SymbolicReopen, database.SymbolicBlank}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kinds := symbolicKinds(election); !reflect.DeepEqual(kinds, []database.SymbolicKind{database.SymbolicReopen, database.SymbolicBlank}) {
+		t.Errorf("symbolic candidates = %v, want reopen then blank", kinds)
+	}
+	for _, candidate := range election.Candidates {
+		if candidate.SymbolicKind == database.SymbolicReopen && candidate.Name != "Nyval" {
+			t.Errorf("reopen candidate is named %q, want Nyval", candidate.Name)
+		}
+	}
+
+	invalidKinds := [][]database.SymbolicKind{
+		{"abstain"},
+		{database.SymbolicBlank, database.SymbolicBlank},
+	}
+	for _, kinds := range invalidKinds {
+		if _, err := elections.SetSymbolicCandidates(election.ID, kinds, now); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("setting symbolic candidates %v returned %v, want ErrInvalid", kinds, err)
+		}
+	}
+
+	opened := createElection(t, elections, "Alice")
+	if _, err := elections.SetSymbolicCandidates(opened.ID, nil, openTime.Add(time.Minute)); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("changing symbolic candidates of opened election returned %v, want ErrInvalid", err)
+	}
+}
+
+func TestReservedCandidateNames(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	election := createElection(t, elections)
+
+	for _, name := range []string{"Vakant", "vacant", " BLANK ", "Nyval", "Re-open nominations"} {
+		if _, err := elections.AddCandidate(election.ID, service.CandidateParams{Name: name}, openTime.Add(-time.Hour)); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("adding candidate %q returned %v, want ErrInvalid", name, err)
+		}
+	}
+}
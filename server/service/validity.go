@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	database "durn/server/db"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// ValidityRules are the rules an election must meet to be valid, where zero
+// disables a rule. Turnout is the amount of voters, while the share of
+// non-blank votes counts weighted votes
+type ValidityRules struct {
+	// MinTurnout is the least amount of votes
+	MinTurnout int `json:"minTurnout"`
+	// MinTurnoutShare is the least share of the voter roll, from 0 to 1,
+	// that must vote
+	MinTurnoutShare float64 `json:"minTurnoutShare"`
+	// MinNonBlankShare is the least share of the votes, from 0 to 1, that
+	// must not be blank for anyone to be elected
+	MinNonBlankShare float64 `json:"minNonBlankShare"`
+}
+
+func validateValidityRules(rules ValidityRules) error {
+	if rules.MinTurnout < 0 {
+		return invalid("The minimum turnout can't be negative")
+	}
+	if rules.MinTurnoutShare < 0 || rules.MinTurnoutShare > 1 {
+		return invalid("The minimum turnout share must be between 0 and 1")
+	}
+	if rules.MinNonBlankShare < 0 || rules.MinNonBlankShare > 1 {
+		return invalid("The minimum share of non-blank votes must be between 0 and 1")
+	}
+	return nil
+}
+
+// Validity tells if an election is valid under its validity rules, or void.
+// Reasons describes every rule that is not met. While the ballots are hidden
+// NonBlankShare is left out, and Pending describes the rules that are not
+// checked yet
+type Validity struct {
+	Valid         bool     `json:"valid"`
+	Turnout       int      `json:"turnout"`
+	Electorate    int      `json:"electorate"`
+	NonBlankShare *float64 `json:"nonBlankShare,omitempty"`
+	Reasons       []string `json:"reasons"`
+	Pending       []string `json:"pending,omitempty"`
+}
+
+// validityOf checks the votes of the election against its validity rules. A
+// ballot is blank if blank is ranked first among the candidates that are
+// running. An election without votes is always void. If the ballots are
+// hidden, only the rules on the turnout are checked
+func validityOf(election database.Election, ballotsHidden bool) Validity {
+	blank := map[uuid.UUID]bool{}
+	withdrawn := map[uuid.UUID]bool{}
+	for _, candidate := range election.Candidates {
+		blank[candidate.ID] = isBlank(candidate)
+		withdrawn[candidate.ID] = candidate.Withdrawn
+	}
+
+	total, nonBlank := 0, 0
+	for _, vote := range election.Votes {
+		total += vote.Weight
+		for _, candidate := range BallotOf(vote) {
+			if withdrawn[candidate] {
+				continue
+			}
+			if !blank[candidate] {
+				nonBlank += vote.Weight
+			}
+			break
+		}
+	}
+
+	result := Validity{
+		Valid:      true,
+		Turnout:    len(election.Votes),
+		Electorate: election.Electorate,
+		Reasons:    []string{},
+	}
+	nonBlankShare := 0.0
+	if total > 0 {
+		nonBlankShare = float64(nonBlank) / float64(total)
+	}
+	if !ballotsHidden {
+		result.NonBlankShare = &nonBlankShare
+	}
+	void := func(format string, args ...any) {
+		result.Valid = false
+		result.Reasons = append(result.Reasons, fmt.Sprintf(format, args...))
+	}
+	if result.Turnout == 0 {
+		void("No votes were cast")
+	}
+	if election.MinTurnout > 0 && result.Turnout < election.MinTurnout {
+		void("The turnout of %d votes is below the quorum of %d votes", result.Turnout, election.MinTurnout)
+	}
+	if election.MinTurnoutShare > 0 && float64(result.Turnout) < election.MinTurnoutShare*float64(election.Electorate) {
+		void("The turnout of %d of %d voters is below the quorum of %s", result.Turnout, election.Electorate, percent(election.MinTurnoutShare))
+	}
+	if election.MinNonBlankShare > 0 && ballotsHidden {
+		result.Pending = append(result.Pending, fmt.Sprintf("The share of non-blank votes, of which %s is needed, is checked when voting closes", percent(election.MinNonBlankShare)))
+	} else if election.MinNonBlankShare > 0 && nonBlankShare < election.MinNonBlankShare {
+		void("%s of the votes are not blank, but %s is needed", percent(nonBlankShare), percent(election.MinNonBlankShare))
+	}
+	return result
+}
+
+func percent(share float64) string {
+	return fmt.Sprintf("%.4g%%", share*100)
+}
+
+// Validity checks a finalized election against its validity rules. Voting
+// has ended once the election is finalized, so the rules on the turnout are
+// checked also before the close time, while the share of non-blank votes
+// depends on the ballots and waits until then
+func (s *Votes) Validity(electionId uuid.UUID, now time.Time) (Validity, error) {
+	election, err := s.repo.GetElectionWithVotes(electionId)
+	if errors.Is(err, ErrNotFound) {
+		return Validity{}, ErrInvalidElection
+	} else if err != nil {
+		return Validity{}, err
+	}
+	if !election.Finalized {
+		return Validity{}, ErrNotFinalized
+	}
+	return validityOf(election, resultsHidden(election, now)), nil
+}
+
+// closedElection fetches a finalized election that has closed, with its votes
+func (s *Votes) closedElection(electionId uuid.UUID, now time.Time) (database.Election, error) {
+	election, err := s.repo.GetElectionWithVotes(electionId)
+	if errors.Is(err, ErrNotFound) {
+		return election, ErrInvalidElection
+	} else if err != nil {
+		return election, err
+	}
+	if !election.Finalized {
+		return election, ErrNotFinalized
+	}
+	if resultsHidden(election, now) {
+		return election, ErrResultsHidden
+	}
+	return election, nil
+}
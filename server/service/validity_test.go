@@ -0,0 +1,184 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/service"
+	"durn/server/service/servicetest"
+	"durn/server/util"
+)
+
+func TestValidity(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	voters := service.NewVoters(repo)
+	votes := service.NewVotes(repo)
+	if err := voters.Add([]string{"a@kth.se", "b@kth.se", "c@kth.se"}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := voters.Add([]string{"delegate@kth.se"}, "", weightOf(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	// validity creates and finalizes an election with the rules, where the
+	// voters have voted blank or for Alice
+	validity := func(rules service.ValidityRules, blankVotes map[string]bool) service.Validity {
+		t.Helper()
+		params := service.DefaultElectionParams()
+		params.Name = "Ordförande"
+		params.OpenTime = util.NullTime{Time: openTime, Valid: true}
+		params.CloseTime = util.NullTime{Time: closeTime, Valid: true}
+		params.SymbolicCandidates = []database.SymbolicKind{database.SymbolicBlank}
+		params.ValidityRules = rules
+		election, err := elections.Create(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		alice, err := elections.AddCandidate(election.ID, service.CandidateParams{Name: "Alice"}, openTime.Add(-time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		blank := election.Candidates[0]
+		for email, isBlank := range blankVotes {
+			ballot := service.Ballot{alice.ID, blank.ID}
+			if isBlank {
+				ballot = service.Ballot{blank.ID, alice.ID}
+			}
+			if _, err := votes.Cast(election.ID, email, "", ballot, openTime.Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := votes.Validity(election.ID, afterClose); !errors.Is(err, service.ErrNotFinalized) {
+			t.Errorf("Validity() before finalizing = %v, want ErrNotFinalized", err)
+		}
+		if _, err := elections.Finalize(election.ID, nil); err != nil {
+			t.Fatal(err)
+		}
+		result, err := votes.Validity(election.ID, afterClose)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name  string
+		rules service.ValidityRules
+		votes map[string]bool
+		valid bool
+	}{
+		{"no rules", service.ValidityRules{}, map[string]bool{"a@kth.se": true}, true},
+		{"no votes", service.ValidityRules{}, map[string]bool{}, false},
+		{"no votes below quorum", service.ValidityRules{MinTurnout: 1}, map[string]bool{}, false},
+		{"quorum reached", service.ValidityRules{MinTurnout: 2}, map[string]bool{"a@kth.se": false, "b@kth.se": false}, true},
+		{"below quorum", service.ValidityRules{MinTurnout: 3}, map[string]bool{"a@kth.se": false, "b@kth.se": false}, false},
+		{"share reached", service.ValidityRules{MinTurnoutShare: 0.5}, map[string]bool{"a@kth.se": false, "b@kth.se": false}, true},
+		{"below share", service.ValidityRules{MinTurnoutShare: 0.5}, map[string]bool{"a@kth.se": false}, false},
+		{"enough non-blank", service.ValidityRules{MinNonBlankShare: 0.5}, map[string]bool{"a@kth.se": true, "b@kth.se": false}, true},
+		{"too many blank", service.ValidityRules{MinNonBlankShare: 0.5}, map[string]bool{"a@kth.se": true, "b@kth.se": true, "c@kth.se": false}, false},
+		// Blank votes are weighted, while the turnout counts voters
+		{"weighted blank", service.ValidityRules{MinNonBlankShare: 0.5}, map[string]bool{"delegate@kth.se": true, "a@kth.se": false, "b@kth.se": false}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := validity(test.rules, test.votes)
+			if result.Valid != test.valid || result.Valid != (len(result.Reasons) == 0) {
+				t.Errorf("Validity() = %+v, want valid %v", result, test.valid)
+			}
+			if result.Turnout != len(test.votes) || result.Electorate != 4 {
+				t.Errorf("Turnout, Electorate = %d, %d, want %d, 4", result.Turnout, result.Electorate, len(test.votes))
+			}
+		})
+	}
+}
+
+func TestValidityBeforeClose(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	params := service.DefaultElectionParams()
+	params.Name = "Ordförande"
+	params.OpenTime = util.NullTime{Time: openTime, Valid: true}
+	params.CloseTime = util.NullTime{Time: closeTime, Valid: true}
+	params.SymbolicCandidates = []database.SymbolicKind{database.SymbolicBlank}
+	params.MinNonBlankShare = 1
+	election, err := elections.Create(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blank := service.Ballot{election.Candidates[0].ID}
+	if _, err := votes.Cast(election.ID, "a@kth.se", "", blank, openTime.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := elections.Finalize(election.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The share of non-blank votes comes from the ballots, so it is left
+	// out until voting has closed
+	result, err := votes.Validity(election.ID, closeTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.NonBlankShare != nil || result.Turnout != 1 || len(result.Pending) != 1 {
+		t.Errorf("Validity() before close = %+v, want valid with the share of non-blank votes pending", result)
+	}
+	result, err = votes.Validity(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.NonBlankShare == nil || *result.NonBlankShare != 0 || len(result.Pending) != 0 {
+		t.Errorf("Validity() after close = %+v, want void without non-blank votes", result)
+	}
+}
+
+func TestCountWithoutVotes(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob")
+	if _, err := elections.Finalize(election.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Counting an election without votes gives a void result
+	result, err := votes.CountSchulze(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalVotes != 0 || result.Validity == nil || result.Validity.Valid {
+		t.Errorf("CountSchulze() = %+v, want no votes and void", result)
+	}
+	if want := []string{"No votes were cast"}; result.Validity != nil && !util.SameSet(result.Validity.Reasons, want) {
+		t.Errorf("Reasons = %v, want %v", result.Validity.Reasons, want)
+	}
+	if _, err := votes.CountIRV(election.ID, afterClose); err != nil {
+		t.Errorf("CountIRV() = %v", err)
+	}
+}
+
+func TestValidityRulesInvalid(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	election := createElection(t, elections, "Alice")
+
+	negative, tooLarge := -1, 1.5
+	for _, changes := range []service.ElectionChanges{
+		{MinTurnout: &negative},
+		{MinTurnoutShare: &tooLarge},
+		{MinNonBlankShare: &tooLarge},
+	} {
+		if _, err := elections.Edit(election.ID, changes); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("Edit(%+v) = %v, want ErrInvalid", changes, err)
+		}
+	}
+	params := service.DefaultElectionParams()
+	params.MinTurnoutShare = -0.1
+	if _, err := elections.Create(params); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("Create() with a negative share = %v, want ErrInvalid", err)
+	}
+}
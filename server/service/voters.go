@@ -0,0 +1,83 @@
+package service
+
+import (
+	database "durn/server/db"
+	"durn/server/util"
+)
+
+// Voters manages the voter roll
+type Voters struct {
+	repo Repository
+}
+
+func NewVoters(repo Repository) *Voters {
+	return &Voters{repo: repo}
+}
+
+// Add adds all valid email addresses to the voter roll, getting
+// notifications in the locale and with votes of the weight. It silently skips
+// all strings that are not valid email addresses, and only updates the locale
+// and weight of addresses that are already in the roll. An empty locale is
+// util.DefaultLocale. Without a weight, new voters get weight 1 and voters
+// already in the roll keep their weight
+func (s *Voters) Add(emails []string, locale string, weight *int) error {
+	if locale == "" {
+		locale = util.DefaultLocale
+	}
+	if !util.IsLocale(locale) {
+		return invalid("Unsupported locale '%s'", locale)
+	}
+	newWeight := 1
+	if weight != nil {
+		if *weight < 1 {
+			return invalid("The weight of a vote must be at least 1")
+		}
+		newWeight = *weight
+	}
+	var voters []database.ValidVoter
+	for _, voter := range emails {
+		if util.ValidEmail(voter) {
+			voters = append(voters, database.ValidVoter{Email: voter, Locale: locale, Weight: newWeight})
+		}
+	}
+	return s.repo.AddVoters(voters, weight != nil)
+}
+
+// Remove removes the email addresses from the voter roll, ignoring
+// addresses that are not in the roll
+func (s *Voters) Remove(emails []string) error {
+	return s.repo.RemoveVoters(emails)
+}
+
+// List fetches the email addresses of all voters
+func (s *Voters) List() ([]string, error) {
+	voters, err := s.repo.ListVoters()
+	if err != nil {
+		return nil, err
+	}
+	result := []string{}
+	for _, voter := range voters {
+		result = append(result, voter.Email)
+	}
+	return result, nil
+}
+
+// Weights fetches the weight of every voter whose weight is not 1, by email
+func (s *Voters) Weights() (map[string]int, error) {
+	voters, err := s.repo.ListVoters()
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]int{}
+	for _, voter := range voters {
+		if voter.Weight != 1 {
+			result[voter.Email] = voter.Weight
+		}
+	}
+	return result, nil
+}
+
+// IsAllowed checks if the email address is in the voter roll
+func (s *Voters) IsAllowed(email string) (bool, error) {
+	return s.repo.IsVoter(email)
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Votes handles casting and counting of votes
+type Votes struct {
+	repo Repository
+}
+
+func NewVotes(repo Repository) *Votes {
+	return &Votes{repo: repo}
+}
+
+// Cast submits a vote for the user in the specified election, at the given time.
+// Validates that it is possible to vote in the election at that time, and
+// that the ballot ranks all candidates of the election.
+// If the user already has a vote, it is replaced.
+// The vote gets the user's weight in the voter roll, or 1 if the user is not
+// in it. Assumes that the user has the right to vote. Returns the unsigned receipt,
+// whose commitment is published with the ballot on the bulletin board. If the
+// secret is empty a random one is used, so that the commitment can't be
+// guessed from the email.
+// A vote of the user's own replaces any vote cast for them with a proxy
+func (s *Votes) Cast(electionId uuid.UUID, email string, secret string, ballot Ballot, now time.Time) (Receipt, error) {
+	return s.cast(electionId, email, "", secret, ballot, now)
+}
+
+// CastByProxy submits a vote for the grantor, cast by the holder of a proxy
+// from them, like Cast. Assumes that the proxy has been checked with
+// Proxies.Authorize. The grantor's own vote takes precedence, so this fails
+// with ErrGrantorVoted if the grantor has already voted themselves, while an
+// earlier vote with the proxy is replaced
+func (s *Votes) CastByProxy(electionId uuid.UUID, holder string, grantor string, secret string, ballot Ballot, now time.Time) (Receipt, error) {
+	return s.cast(electionId, grantor, holder, secret, ballot, now)
+}
+
+// cast submits the vote of the user, cast by the holder of a proxy from them
+// unless proxy is empty
+func (s *Votes) cast(electionId uuid.UUID, email string, proxy string, secret string, ballot Ballot, now time.Time) (Receipt, error) {
+	userHash := util.GetVoteHash(email, electionId)
+
+	// Validation section
+	// Check that election is open for voting, that all candidates are accounted for
+	// the vote, and that no extra candidates (or invalid ones) are included
+	election, err := getElection(s.repo, electionId)
+	if err != nil { // Information should not be leaked if elections is not public
+		return Receipt{}, err
+	}
+	if election.Finalized || !util.TimeIsInValidInterval(
+		now, election.OpenTime, election.CloseTime,
+	) {
+		return Receipt{}, ErrVotingClosed
+	}
+	if err := ValidateBallot(election.Candidates, ballot); err != nil {
+		return Receipt{}, err
+	}
+	if secret == "" {
+		random := make([]byte, 16)
+		if _, err := rand.Read(random); err != nil {
+			return Receipt{}, err
+		}
+		secret = hex.EncodeToString(random)
+	}
+	receipt := NewReceipt(electionId, email, secret, ballot, now)
+	weight := 1
+	if voter, err := s.repo.GetVoter(email); err == nil {
+		weight = voter.Weight
+	} else if !errors.Is(err, ErrNotFound) {
+		return Receipt{}, err
+	}
+	var ciphertext []byte
+	if election.EncryptionKey != "" {
+		if ciphertext, err = encryptBallot(election, ballot); err != nil {
+			return Receipt{}, err
+		}
+	}
+
+	// Insertion section
+	err = s.repo.Transaction(func(repo Repository) error {
+		castedVote, err := repo.GetCastedVote(email, electionId)
+		if errors.Is(err, ErrNotFound) {
+			castedVote = database.CastedVote{Email: email, ElectionID: electionId, Proxy: proxy}
+			if err := repo.CreateCastedVote(&castedVote); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		} else if proxy != "" && castedVote.Proxy == "" {
+			return ErrGrantorVoted
+		} else if castedVote.Proxy != proxy {
+			castedVote.Proxy = proxy
+			if err := repo.SaveCastedVote(&castedVote); err != nil {
+				return err
+			}
+		}
+
+		vote, err := repo.FindVoteByUserHash(userHash)
+		if errors.Is(err, ErrNotFound) {
+			vote = database.Vote{
+				ID:         uuid.NewV4(),
+				VoteTime:   now,
+				ElectionID: electionId,
+				UserHash:   userHash,
+				Weight:     weight,
+			}
+			if err := repo.CreateVote(&vote); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		} else if vote.Weight != weight {
+			vote.Weight = weight
+			if err := repo.SaveVote(&vote); err != nil {
+				return err
+			}
+		}
+
+		// Encrypted ballots get their rankings when they are decrypted
+		var rankings []database.Ranking
+		if ciphertext != nil {
+			if err := repo.SaveEncryptedBallot(&database.EncryptedBallot{
+				VoteID:     vote.ID,
+				ElectionID: electionId,
+				Ciphertext: ciphertext,
+			}); err != nil {
+				return err
+			}
+		} else {
+			for rank, candidateID := range ballot {
+				rankings = append(rankings, database.Ranking{
+					VoteID:      vote.ID,
+					Rank:        rank,
+					CandidateID: candidateID,
+				})
+			}
+		}
+		if err := repo.ReplaceRankings(vote.ID, rankings); err != nil {
+			return err
+		}
+		return repo.SaveVoteHash(&database.VoteHash{
+			Hash:       receipt.Commitment,
+			ElectionID: electionId,
+			VoteID:     vote.ID,
+		})
+	})
+	if err != nil {
+		return Receipt{}, err
+	}
+	return receipt, nil
+}
+
+// BallotRecord is a vote as returned when listing votes
+type BallotRecord struct {
+	Time       time.Time   `json:"time"`
+	Weight     int         `json:"weight"`
+	IsBlank    bool        `json:"blank"`
+	ElectionID uuid.UUID   `json:"election"`
+	Rankings   []uuid.UUID `json:"rankings"`
+}
+
+// resultsHidden reports whether the ballots and results of the election must
+// be hidden at the given time, which they are until voting has closed. An
+// election without a close time only closes when it is finalized. Only the
+// turnout, from Count, is available before then
+func resultsHidden(election database.Election, now time.Time) bool {
+	if !election.CloseTime.Valid {
+		return !election.Finalized
+	}
+	return !now.After(election.CloseTime.Time)
+}
+
+// List returns all votes of an election that has closed
+func (s *Votes) List(electionId uuid.UUID, now time.Time) ([]BallotRecord, error) {
+	election, err := getElection(s.repo, electionId)
+	if err != nil {
+		return nil, err
+	}
+	if resultsHidden(election, now) {
+		return nil, ErrResultsHidden
+	}
+	votes, err := s.repo.ListVotes(electionId)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []BallotRecord
+	for _, vote := range votes {
+		result = append(result, BallotRecord{
+			Time:       vote.VoteTime,
+			Weight:     vote.Weight,
+			ElectionID: vote.ElectionID,
+			Rankings:   BallotOf(vote),
+		})
+	}
+	return result, nil
+}
+
+// Count returns the amount of votes in an election
+func (s *Votes) Count(electionId uuid.UUID) (int64, error) {
+	return s.repo.CountVotes(electionId)
+}
+
+// HasVoted checks if the user has voted in the election
+func (s *Votes) HasVoted(electionId uuid.UUID, email string) (bool, error) {
+	return s.repo.HasCastedVote(email, electionId)
+}
+
+// Hashes returns the receipt commitments of all votes in finalized elections
+func (s *Votes) Hashes() ([]string, error) {
+	elections, err := s.repo.ListElections()
+	if err != nil {
+		return nil, err
+	}
+	result := []string{}
+	for _, election := range elections {
+		if !election.Finalized {
+			continue
+		}
+		hashes, err := s.repo.ListVoteHashes(election.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, hash := range hashes {
+			result = append(result, hash.Hash)
+		}
+	}
+	return result, nil
+}
+
+// countableElection fetches a finalized and closed election, together with
+// the ballots of all its votes and their weights
+func (s *Votes) countableElection(electionId uuid.UUID, now time.Time) (database.Election, []Ballot, []int, error) {
+	election, err := s.closedElection(electionId, now)
+	if err != nil {
+		return election, nil, nil, err
+	}
+
+	ballots := make([]Ballot, len(election.Votes))
+	weights := make([]int, len(election.Votes))
+	for i, vote := range election.Votes {
+		ballots[i] = BallotOf(vote)
+		weights[i] = vote.Weight
+	}
+	return election, ballots, weights, nil
+}
+
+// CountSchulze counts the weighted votes of a finalized election that has
+// closed with the Schulze method. Withdrawn candidates are skipped. The result
+// includes the validity of the election under its validity rules, which is
+// void if there are no votes
+func (s *Votes) CountSchulze(electionId uuid.UUID, now time.Time) (SchulzeResult, error) {
+	election, ballots, weights, err := s.countableElection(electionId, now)
+	if err != nil {
+		return SchulzeResult{}, err
+	}
+	candidates, ballots := RemoveWithdrawn(election.Candidates, ballots)
+	result := Schulze(candidates, ballots, weights)
+	validity := validityOf(election, false)
+	result.Validity = &validity
+	return result, nil
+}
+
+// CountIRV counts the weighted votes of a finalized election that has closed
+// with instant runoff voting. Withdrawn candidates are skipped. The validity
+// of the election is not included, see Validity
+func (s *Votes) CountIRV(electionId uuid.UUID, now time.Time) ([]IRVStage, error) {
+	election, ballots, weights, err := s.countableElection(electionId, now)
+	if err != nil {
+		return nil, err
+	}
+	candidates, ballots := RemoveWithdrawn(election.Candidates, ballots)
+	return IRV(candidates, ballots, weights), nil
+}
@@ -0,0 +1,509 @@
+package service_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	database "durn/server/db"
+	"durn/server/service"
+	"durn/server/service/servicetest"
+	"durn/server/util"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	openTime  = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	closeTime = time.Date(2023, 3, 1, 18, 0, 0, 0, time.UTC)
+	// afterClose is when the results of elections created by createElection
+	// can be seen
+	afterClose = closeTime.Add(time.Minute)
+)
+
+// createElection creates an election that is open between openTime and
+// closeTime, with the given candidates in addition to the vacant candidate
+func createElection(t *testing.T, elections *service.Elections, candidates ...string) database.Election {
+	t.Helper()
+	params := service.DefaultElectionParams()
+	params.Name = "Ordförande"
+	params.OpenTime = util.NullTime{Time: openTime, Valid: true}
+	params.CloseTime = util.NullTime{Time: closeTime, Valid: true}
+	election, err := elections.Create(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range candidates {
+		if _, err := elections.AddCandidate(election.ID, service.CandidateParams{Name: name}, openTime.Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	election, err = elections.Get(election.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return election
+}
+
+// weightOf returns the weight to give to Voters.Add
+func weightOf(weight int) *int {
+	return &weight
+}
+
+func ballotOf(candidates []database.Candidate) service.Ballot {
+	var ballot service.Ballot
+	for _, candidate := range candidates {
+		ballot = append(ballot, candidate.ID)
+	}
+	return ballot
+}
+
+func TestCastVotingWindow(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob")
+	ballot := ballotOf(election.Candidates)
+
+	tests := []struct {
+		name string
+		time time.Time
+		err  error
+	}{
+		{"before open", openTime.Add(-time.Minute), service.ErrVotingClosed},
+		{"at open", openTime, nil},
+		{"while open", openTime.Add(time.Hour), nil},
+		{"at close", closeTime, nil},
+		{"after close", closeTime.Add(time.Minute), service.ErrVotingClosed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := votes.Cast(election.ID, "voter@kth.se", "", ballot, test.time)
+			if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+				t.Errorf("Cast() = %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestCastWithoutInterval(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election, err := elections.Create(service.DefaultElectionParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = votes.Cast(election.ID, "voter@kth.se", "", ballotOf(election.Candidates), openTime)
+	if !errors.Is(err, service.ErrVotingClosed) {
+		t.Errorf("Cast() = %v, want ErrVotingClosed", err)
+	}
+}
+
+func TestCastFinalized(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice")
+	if _, err := elections.Finalize(election.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := votes.Cast(election.ID, "voter@kth.se", "", ballotOf(election.Candidates), openTime.Add(time.Hour))
+	if !errors.Is(err, service.ErrVotingClosed) {
+		t.Errorf("Cast() = %v, want ErrVotingClosed", err)
+	}
+}
+
+func TestCastInvalid(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob")
+	other := createElection(t, elections, "Carol")
+	now := openTime.Add(time.Hour)
+
+	ballot := ballotOf(election.Candidates)
+	tests := []struct {
+		name     string
+		election uuid.UUID
+		ballot   service.Ballot
+		err      error
+	}{
+		{"missing candidate", election.ID, ballot[:2], service.ErrInvalidBallot},
+		{"duplicate candidate", election.ID, append(ballot[:2:2], ballot[0]), service.ErrInvalidBallot},
+		{"candidate of other election", election.ID, append(ballot[:2:2], other.Candidates[0].ID), service.ErrInvalidBallot},
+		{"unknown election", uuid.NewV4(), ballot, service.ErrInvalidElection},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := votes.Cast(test.election, "voter@kth.se", "", test.ballot, now); !errors.Is(err, test.err) {
+				t.Errorf("Cast() = %v, want %v", err, test.err)
+			}
+		})
+	}
+
+	if count, _ := votes.Count(election.ID); count != 0 {
+		t.Errorf("invalid votes were stored, got %d votes", count)
+	}
+}
+
+func TestCastReplacesVote(t *testing.T) {
+	db := servicetest.NewDB(t)
+	repo := service.NewGormRepository(db)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob")
+	now := openTime.Add(time.Hour)
+
+	first := ballotOf(election.Candidates)
+	second := service.Ballot{first[2], first[1], first[0]}
+
+	if _, err := votes.Cast(election.ID, "voter@kth.se", "", first, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := votes.Cast(election.ID, "voter@kth.se", "", second, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := votes.Cast(election.ID, "other@kth.se", "", first, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if count, err := votes.Count(election.ID); err != nil || count != 2 {
+		t.Errorf("Count() = %d, %v, want 2", count, err)
+	}
+
+	var castedVotes int64
+	db.Model(&database.CastedVote{}).Where("email = ?", "voter@kth.se").Count(&castedVotes)
+	if castedVotes != 1 {
+		t.Errorf("got %d casted votes for the voter, want 1", castedVotes)
+	}
+
+	hash := util.GetVoteHash("voter@kth.se", election.ID)
+	stored, err := repo.ListVotes(election.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, vote := range stored {
+		if vote.UserHash != hash {
+			continue
+		}
+		if got := service.BallotOf(vote); !reflect.DeepEqual(got, second) {
+			t.Errorf("ballot after replacing = %v, want %v", got, second)
+		}
+	}
+
+	if voted, err := votes.HasVoted(election.ID, "voter@kth.se"); err != nil || !voted {
+		t.Errorf("HasVoted() = %v, %v, want true", voted, err)
+	}
+}
+
+func TestCountSchulze(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob")
+	now := openTime.Add(time.Hour)
+
+	if _, err := votes.CountSchulze(election.ID, afterClose); !errors.Is(err, service.ErrNotFinalized) {
+		t.Errorf("CountSchulze() before finalizing = %v, want ErrNotFinalized", err)
+	}
+
+	byName := map[string]uuid.UUID{}
+	for _, candidate := range election.Candidates {
+		byName[candidate.Name] = candidate.ID
+	}
+	bobFirst := service.Ballot{byName["Bob"], byName["Alice"], byName[util.VacantCandidate]}
+	aliceFirst := service.Ballot{byName["Alice"], byName["Bob"], byName[util.VacantCandidate]}
+	for i, ballot := range []service.Ballot{bobFirst, bobFirst, aliceFirst} {
+		email := string(rune('a'+i)) + "@kth.se"
+		if _, err := votes.Cast(election.ID, email, "", ballot, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := elections.Finalize(election.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Finalizing early does not show the results before voting has closed
+	if _, err := votes.CountSchulze(election.ID, closeTime); !errors.Is(err, service.ErrResultsHidden) {
+		t.Errorf("CountSchulze() before close = %v, want ErrResultsHidden", err)
+	}
+	if _, err := votes.List(election.ID, closeTime); !errors.Is(err, service.ErrResultsHidden) {
+		t.Errorf("List() before close = %v, want ErrResultsHidden", err)
+	}
+	if count, err := votes.Count(election.ID); err != nil || count != 3 {
+		t.Errorf("Count() before close = %d, %v, want 3", count, err)
+	}
+
+	result, err := votes.CountSchulze(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalVotes != 3 {
+		t.Errorf("TotalVotes = %d, want 3", result.TotalVotes)
+	}
+	if result.Ranking[0].Name != "Bob" || result.Ranking[1].Name != "Alice" {
+		t.Errorf("Ranking = %v, want Bob, Alice, Vakant", result.Ranking)
+	}
+}
+
+func TestResultsHiddenWithoutCloseTime(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice")
+	if _, err := votes.Cast(election.ID, "a@kth.se", "", ballotOf(election.Candidates), openTime.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	election.CloseTime.Valid = false
+	if err := repo.SaveElection(&election); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a close time voting only closes when the election is finalized
+	if _, err := votes.List(election.ID, afterClose.Add(24*time.Hour)); !errors.Is(err, service.ErrResultsHidden) {
+		t.Errorf("List() before finalizing = %v, want ErrResultsHidden", err)
+	}
+	if _, err := elections.Finalize(election.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := votes.List(election.ID, afterClose); err != nil || len(list) != 1 {
+		t.Errorf("List() after finalizing = %v, %v, want one vote", list, err)
+	}
+}
+
+func TestEditCloseTimeWithVotes(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice")
+
+	earlier := util.NullTime{Time: closeTime.Add(-time.Hour), Valid: true}
+	if _, err := elections.Edit(election.ID, service.ElectionChanges{CloseTime: &earlier}); err != nil {
+		t.Fatalf("Edit() without votes = %v", err)
+	}
+	if _, err := votes.Cast(election.ID, "a@kth.se", "", ballotOf(election.Candidates), openTime.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The close time decides when the results can be seen, so it is fixed
+	// once there are votes. Sending the same close time is not a change
+	name := "Kassör"
+	if _, err := elections.Edit(election.ID, service.ElectionChanges{Name: &name, CloseTime: &earlier}); err != nil {
+		t.Errorf("Edit() with the same close time = %v", err)
+	}
+	for _, closeTime := range []util.NullTime{{Time: openTime.Add(time.Hour), Valid: true}, {}} {
+		if _, err := elections.Edit(election.ID, service.ElectionChanges{CloseTime: &closeTime}); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("Edit() to close time %v with votes = %v, want ErrInvalid", closeTime, err)
+		}
+	}
+}
+
+func TestEditValidityRulesWithVotes(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice")
+
+	quorum := 2
+	if _, err := elections.Edit(election.ID, service.ElectionChanges{MinTurnout: &quorum}); err != nil {
+		t.Fatalf("Edit() without votes = %v", err)
+	}
+	if _, err := votes.Cast(election.ID, "a@kth.se", "", ballotOf(election.Candidates), openTime.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The validity rules decide whether the election is void, so they are
+	// fixed once the turnout can be seen. Sending the same rules is not a
+	// change
+	name := "Kassör"
+	if _, err := elections.Edit(election.ID, service.ElectionChanges{Name: &name, MinTurnout: &quorum}); err != nil {
+		t.Errorf("Edit() with the same rules = %v", err)
+	}
+	turnout, share := 1, 0.5
+	for _, changes := range []service.ElectionChanges{
+		{MinTurnout: &turnout},
+		{MinTurnoutShare: &share},
+		{MinNonBlankShare: &share},
+	} {
+		if _, err := elections.Edit(election.ID, changes); !errors.Is(err, service.ErrInvalid) {
+			t.Errorf("Edit(%+v) with votes = %v, want ErrInvalid", changes, err)
+		}
+	}
+}
+
+func TestAddVotersKeepsWeight(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	voters := service.NewVoters(repo)
+	if err := voters.Add([]string{"delegate@kth.se"}, "sv", weightOf(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Adding the voter again without a weight only changes the locale
+	if err := voters.Add([]string{"delegate@kth.se", "a@kth.se"}, "en", nil); err != nil {
+		t.Fatal(err)
+	}
+	weights, err := voters.Weights()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weights) != 1 || weights["delegate@kth.se"] != 3 {
+		t.Errorf("Weights() = %v, want only delegate@kth.se with 3", weights)
+	}
+	voter, err := repo.GetVoter("delegate@kth.se")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if voter.Locale != "en" {
+		t.Errorf("locale = %q, want en", voter.Locale)
+	}
+
+	if err := voters.Add([]string{"delegate@kth.se"}, "en", weightOf(1)); err != nil {
+		t.Fatal(err)
+	}
+	if weights, err := voters.Weights(); err != nil || len(weights) != 0 {
+		t.Errorf("Weights() after setting weight 1 = %v, %v, want none", weights, err)
+	}
+	if err := voters.Add([]string{"delegate@kth.se"}, "en", weightOf(0)); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("Add() with weight 0 = %v, want ErrInvalid", err)
+	}
+}
+
+func TestWeightedVotes(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	voters := service.NewVoters(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob")
+	now := openTime.Add(time.Hour)
+
+	if err := voters.Add([]string{"a@kth.se", "b@kth.se"}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := voters.Add([]string{"delegate@kth.se"}, "", weightOf(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := voters.Add([]string{"c@kth.se"}, "", weightOf(-1)); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("Add() with a negative weight = %v, want ErrInvalid", err)
+	}
+
+	ballot := ballotOf(election.Candidates)
+	bobFirst := service.Ballot{ballot[1], ballot[0], ballot[2]}
+	for _, email := range []string{"a@kth.se", "b@kth.se", "delegate@kth.se"} {
+		if _, err := votes.Cast(election.ID, email, "", ballot, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The weight is updated when the vote is replaced
+	if err := voters.Add([]string{"delegate@kth.se"}, "", weightOf(3)); err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := votes.Cast(election.ID, "delegate@kth.se", "", bobFirst, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := elections.Finalize(election.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := votes.CountSchulze(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalVotes != 3 || result.WeightedVotes != 5 {
+		t.Errorf("TotalVotes, WeightedVotes = %d, %d, want 3, 5", result.TotalVotes, result.WeightedVotes)
+	}
+	if result.Ranking[0].Name != "Bob" {
+		t.Errorf("Ranking = %v, want Bob first", result.Ranking)
+	}
+
+	// The bulletin board and the export list the ballot of the delegate
+	// once for each unit of its weight, and recounting them gives the same
+	// weighted result
+	board, err := votes.BulletinBoard(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	export, err := votes.Export(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, export := range []service.BallotExport{board.BallotExport(), export} {
+		if len(export.Ballots) != 5 || export.Weights != nil {
+			t.Errorf("export has %d ballots and weights %v, want 5 ballots without weights", len(export.Ballots), export.Weights)
+		}
+		recount := service.Schulze(export.CandidateList(), export.Ballots, export.Weights)
+		if !reflect.DeepEqual(recount.VoteMatrix, result.VoteMatrix) {
+			t.Errorf("recount VoteMatrix = %v, want %v", recount.VoteMatrix, result.VoteMatrix)
+		}
+	}
+	proof, err := votes.Proof(election.ID, receipt.Commitment, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proof.Verify() || !reflect.DeepEqual(proof.Entry.Ballot, bobFirst) {
+		t.Errorf("proof of the delegate = %+v, want a verified proof of their ballot", proof)
+	}
+}
+
+func TestWithdrawCandidate(t *testing.T) {
+	repo := servicetest.NewRepository(t)
+	elections := service.NewElections(repo)
+	votes := service.NewVotes(repo)
+	election := createElection(t, elections, "Alice", "Bob", "Carol")
+	now := openTime.Add(time.Hour)
+
+	byName := map[string]uuid.UUID{}
+	for _, candidate := range election.Candidates {
+		byName[candidate.Name] = candidate.ID
+	}
+	vacant := byName[util.VacantCandidate]
+	for i, ballot := range []service.Ballot{
+		{byName["Bob"], byName["Carol"], byName["Alice"], vacant},
+		{byName["Bob"], byName["Carol"], byName["Alice"], vacant},
+		{byName["Alice"], byName["Carol"], byName["Bob"], vacant},
+	} {
+		if _, err := votes.Cast(election.ID, string(rune('a'+i))+"@kth.se", "", ballot, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	withdrawn := true
+	if _, err := elections.EditCandidate(byName["Bob"], service.CandidateChanges{Withdrawn: &withdrawn}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := elections.EditCandidate(vacant, service.CandidateChanges{Withdrawn: &withdrawn}); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("withdrawing a symbolic candidate = %v, want an ErrInvalid error", err)
+	}
+
+	// Ballots still have to rank withdrawn candidates
+	if _, err := votes.Cast(election.ID, "d@kth.se", "", service.Ballot{byName["Carol"], byName["Alice"], vacant}, now); !errors.Is(err, service.ErrInvalidBallot) {
+		t.Errorf("Cast() without the withdrawn candidate = %v, want ErrInvalidBallot", err)
+	}
+	if _, err := votes.Cast(election.ID, "d@kth.se", "", service.Ballot{byName["Carol"], byName["Alice"], byName["Bob"], vacant}, now); err != nil {
+		t.Errorf("Cast() with the withdrawn candidate = %v", err)
+	}
+
+	if _, err := elections.Finalize(election.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	result, err := votes.CountSchulze(election.ID, afterClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ranking []string
+	for _, candidate := range result.Ranking {
+		ranking = append(ranking, candidate.Name)
+	}
+	if want := []string{"Carol", "Alice", util.VacantCandidate}; !reflect.DeepEqual(ranking, want) {
+		t.Errorf("Ranking = %v, want %v", ranking, want)
+	}
+
+	reinstated := false
+	if _, err := elections.EditCandidate(byName["Bob"], service.CandidateChanges{Withdrawn: &reinstated}); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("reinstating after finalizing = %v, want an ErrInvalid error", err)
+	}
+}
@@ -0,0 +1,77 @@
+package util
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops calls to a failing upstream service. After threshold
+// consecutive failures the breaker opens and all calls fail immediately with
+// ErrCircuitOpen. Once the cooldown has passed a single call is let through,
+// closing the breaker again if it succeeds.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	m         sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker creates a circuit breaker. A threshold of zero or less
+// disables the breaker
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Call runs fn unless the breaker is open. Errors for which isFailure returns
+// false are returned as is without counting as failures, e.g. for requests
+// that the upstream rejected rather than failed to handle
+func (b *CircuitBreaker) Call(fn func() error, isFailure func(error) bool) error {
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	err := fn()
+	b.record(err != nil && isFailure(err))
+	return err
+}
+
+// Open reports whether the breaker is currently rejecting calls
+func (b *CircuitBreaker) Open() bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.threshold > 0 && b.failures >= b.threshold
+}
+
+func (b *CircuitBreaker) allow() bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *CircuitBreaker) record(failed bool) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
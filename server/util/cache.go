@@ -0,0 +1,97 @@
+package util
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is a thread safe key-value cache where entries expire after a fixed
+// time to live. When the cache is full the least recently used entry is evicted
+type Cache[K comparable, V any] struct {
+	ttl     time.Duration
+	maxSize int
+
+	m       sync.Mutex
+	entries map[K]*list.Element
+	order   *list.List // front is most recently used
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+func NewCache[K comparable, V any](ttl time.Duration, maxSize int) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[K]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get returns the value stored for the key, if it exists and has not expired
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	var zero V
+	elem, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*cacheEntry[K, V])
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores the value for the key, evicting the least recently used entry
+// if the cache is full. Does nothing if the cache has no capacity or no time to live
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetUntil(key, value, time.Now().Add(c.ttl))
+}
+
+// SetUntil stores the value for the key like Set, but lets it expire at the
+// given time if that is before its time to live
+func (c *Cache[K, V]) SetUntil(key K, value V, expires time.Time) {
+	if c.maxSize <= 0 || c.ttl <= 0 {
+		return
+	}
+	if ttl := time.Now().Add(c.ttl); ttl.Before(expires) {
+		expires = ttl
+	}
+	if !expires.After(time.Now()) {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	entry := &cacheEntry[K, V]{key: key, value: value, expires: expires}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	for c.order.Len() >= c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry[K, V]).key)
+	}
+	c.entries[key] = c.order.PushFront(entry)
+}
+
+// Len returns the amount of entries in the cache, including expired entries
+// that have not been removed yet
+func (c *Cache[K, V]) Len() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.order.Len()
+}
@@ -7,7 +7,9 @@ const (
 	RequestFailedMessage   = "Server failed to handle request"
 )
 
+// Names of the symbolic candidates in the default locale
 const (
 	VacantCandidate = "Vakant"
 	BlankCandidate  = "Blank"
+	ReopenCandidate = "Nyval"
 )
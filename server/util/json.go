@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"time"
@@ -13,6 +14,16 @@ import (
 
 var validate *validator.Validate = validator.New()
 
+// httpClient is used for all requests to other services
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// SetRequestTimeout sets the timeout for all requests to other services
+func SetRequestTimeout(timeout time.Duration) {
+	httpClient.Timeout = timeout
+}
+
+// GetFromUrl fetches the body of the given url. Server errors (5xx) from
+// the other service are returned as errors
 func GetFromUrl(url string, bearerToken string) ([]byte, error) {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
@@ -23,12 +34,16 @@ func GetFromUrl(url string, bearerToken string) ([]byte, error) {
 		req.Header.Set("Authorization", "Bearer " + bearerToken)
 	}
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := httpClient.Do(req)
 	if err != nil {
 		return []byte{}, err
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode >= 500 {
+		return []byte{}, fmt.Errorf("%s responded with status %d", url, res.StatusCode)
+	}
+
 	ret, err := io.ReadAll(res.Body)
 	if err != nil {
 		return []byte{}, err
@@ -37,6 +52,16 @@ func GetFromUrl(url string, bearerToken string) ([]byte, error) {
 	return ret, err
 }
 
+// GetStatus fetches the given url and returns the status code of the response
+func GetStatus(url string) (int, error) {
+	res, err := httpClient.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	return res.StatusCode, nil
+}
+
 func ParseJson[D string | []byte, T any](data D, target *T) error {
 	if err := json.Unmarshal([]byte(data), target); err != nil {
 		return err
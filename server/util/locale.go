@@ -0,0 +1,46 @@
+package util
+
+import (
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is the locale of the names, descriptions and presentations
+// stored directly on elections and candidates
+const DefaultLocale = "sv"
+
+// Locales are the supported locales, starting with the default
+var Locales = []string{DefaultLocale, "en"}
+
+var localeMatcher = language.NewMatcher([]language.Tag{
+	language.Swedish,
+	language.English,
+})
+
+// MatchLocale picks the supported locale closest to the requested one. An
+// explicitly requested locale, such as from a query parameter, is used if it
+// is supported, otherwise the Accept-Language header is matched. Falls back
+// to DefaultLocale
+func MatchLocale(requested string, acceptLanguage string) string {
+	if IsLocale(requested) {
+		return requested
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, index, confidence := localeMatcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return Locales[index]
+}
+
+// IsLocale checks if the locale is supported
+func IsLocale(locale string) bool {
+	for _, supported := range Locales {
+		if locale == supported {
+			return true
+		}
+	}
+	return false
+}
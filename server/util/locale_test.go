@@ -0,0 +1,25 @@
+package util
+
+import "testing"
+
+func TestMatchLocale(t *testing.T) {
+	tests := []struct {
+		requested      string
+		acceptLanguage string
+		want           string
+	}{
+		{"", "", "sv"},
+		{"en", "", "en"},
+		{"en", "sv-SE", "en"},
+		{"de", "en-GB,en;q=0.9", "en"},
+		{"", "en-US,en;q=0.9,sv;q=0.8", "en"},
+		{"", "sv-SE,sv;q=0.9,en;q=0.8", "sv"},
+		{"", "de-DE", "sv"},
+		{"", "not a language", "sv"},
+	}
+	for _, test := range tests {
+		if got := MatchLocale(test.requested, test.acceptLanguage); got != test.want {
+			t.Errorf("MatchLocale(%q, %q) = %q, want %q", test.requested, test.acceptLanguage, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown renders GitHub flavoured markdown. Raw HTML in the source is
+// omitted and links with dangerous schemes such as javascript: are removed,
+// since goldmark is not configured with html.WithUnsafe
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+)
+
+// RenderMarkdown renders markdown written by users as sanitised HTML
+func RenderMarkdown(source string) string {
+	var out bytes.Buffer
+	if err := markdown.Convert([]byte(source), &out); err != nil {
+		fmt.Println(err)
+		return ""
+	}
+	return out.String()
+}
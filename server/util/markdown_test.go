@@ -0,0 +1,30 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    string
+		notWant string
+	}{
+		{"Hej **alla**", "<strong>alla</strong>", ""},
+		{"~~struken~~", "<del>struken</del>", ""},
+		{"<script>alert(1)</script>", "", "<script"},
+		{`<img src=x onerror="alert(1)">`, "", "onerror"},
+		{"[klicka](javascript:alert(1))", "", "javascript:"},
+		{"[länk](https://example.com)", `href="https://example.com"`, ""},
+	}
+	for _, test := range tests {
+		html := RenderMarkdown(test.source)
+		if test.want != "" && !strings.Contains(html, test.want) {
+			t.Errorf("RenderMarkdown(%q) = %q, want it to contain %q", test.source, html, test.want)
+		}
+		if test.notWant != "" && strings.Contains(html, test.notWant) {
+			t.Errorf("RenderMarkdown(%q) = %q, must not contain %q", test.source, html, test.notWant)
+		}
+	}
+}
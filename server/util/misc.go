@@ -2,6 +2,7 @@ package util
 
 import (
 	"database/sql"
+	"fmt"
 	"net/mail"
 	"time"
 )
@@ -68,3 +69,24 @@ func Min(a int, b int) int {
 	}
 	return b
 }
+
+// Retry calls fn until it succeeds, at most the given amount of attempts.
+// The delay between attempts starts at delay and is doubled after each
+// attempt. Returns the last error if all attempts fail
+func Retry(attempts int, delay time.Duration, fn func() error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt < attempts {
+			fmt.Printf("Attempt %d of %d failed, retrying in %s: %s\n", attempt, attempts, delay, err)
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package util
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSameSet(t *testing.T) {
+	tests := []struct {
+		a, b []int
+		want bool
+	}{
+		{nil, nil, true},
+		{[]int{1, 2, 3}, []int{3, 1, 2}, true},
+		{[]int{1, 1, 2}, []int{1, 2, 1}, true},
+		{[]int{1, 1, 2}, []int{1, 2, 2}, false},
+		{[]int{1, 2}, []int{1, 2, 3}, false},
+		{[]int{1, 2, 3}, []int{1, 2, 4}, false},
+	}
+	for _, test := range tests {
+		if got := SameSet(test.a, test.b); got != test.want {
+			t.Errorf("SameSet(%v, %v) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestTimeIsInValidInterval(t *testing.T) {
+	start := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	valid := func(t time.Time) sql.NullTime { return sql.NullTime{Time: t, Valid: true} }
+
+	tests := []struct {
+		name       string
+		time       time.Time
+		start, end sql.NullTime
+		want       bool
+	}{
+		{"before", start.Add(-time.Second), valid(start), valid(end), false},
+		{"at start", start, valid(start), valid(end), true},
+		{"inside", start.Add(time.Minute), valid(start), valid(end), true},
+		{"at end", end, valid(start), valid(end), true},
+		{"after", end.Add(time.Second), valid(start), valid(end), false},
+		{"no start", start.Add(time.Minute), sql.NullTime{}, valid(end), false},
+		{"no end", start.Add(time.Minute), valid(start), sql.NullTime{}, false},
+	}
+	for _, test := range tests {
+		if got := TimeIsInValidInterval(test.time, test.start, test.end); got != test.want {
+			t.Errorf("%s: TimeIsInValidInterval() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	err := Retry(3, time.Millisecond, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("Retry() = %v after %d calls, want nil after 2", err, calls)
+	}
+
+	calls = 0
+	failure := errors.New("failure")
+	err = Retry(3, time.Millisecond, func() error {
+		calls++
+		return failure
+	})
+	if err != failure || calls != 3 {
+		t.Errorf("Retry() = %v after %d calls, want failure after 3", err, calls)
+	}
+}
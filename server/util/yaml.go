@@ -0,0 +1,55 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// YamlToJson converts a YAML document to JSON, so that it can be decoded
+// with the same types and validation as JSON requests. Since YAML is a
+// superset of JSON, JSON documents are converted as well
+func YamlToJson(data []byte) ([]byte, error) {
+	var value any
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	value, err := convertYaml(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// convertYaml converts the maps decoded by yaml, which can have keys of any
+// type, to maps with string keys that can be encoded as JSON
+func convertYaml(value any) (any, error) {
+	switch v := value.(type) {
+	case map[any]any:
+		res := make(map[string]any, len(v))
+		for key, item := range v {
+			s, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported key %v, keys must be strings", key)
+			}
+			converted, err := convertYaml(item)
+			if err != nil {
+				return nil, err
+			}
+			res[s] = converted
+		}
+		return res, nil
+	case []any:
+		for i, item := range v {
+			converted, err := convertYaml(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}